@@ -0,0 +1,302 @@
+package genmai
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// AddColumn adds the column of the struct field to the table.
+// table must be struct or pointer to struct, and field is the name of the
+// struct field. The column definition is the same as CreateTable.
+// If the field has "unique" tag, the unique index will be created as well as
+// AutoMigrate. The primary key column can't be added, and the NOT NULL
+// column must have "default" tag to fill the existing rows.
+//
+//     db.AddColumn(&User{}, "Email")
+func (db *DB) AddColumn(table interface{}, field string) error {
+	return db.AddColumnContext(context.Background(), table, field)
+}
+
+// AddColumnContext is like AddColumn, but with context.
+func (db *DB) AddColumnContext(ctx context.Context, table interface{}, field string) error {
+	m, f, err := db.tableField("AddColumn", table, field)
+	if err != nil {
+		return err
+	}
+	definition, err := db.addColumnDefinition("AddColumn", m, f)
+	if err != nil {
+		return err
+	}
+	queries := []string{fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", db.dialect.Quote(m.tableName), definition)}
+	if f.unique {
+		queries = append(queries, db.uniqueIndexQuery(m, f))
+	}
+	return db.execDDL(ctx, queries)
+}
+
+// DropColumn drops the column from the table.
+// table must be struct or pointer to struct, and column is the column name,
+// because the struct field is usually already removed.
+// SQLite3 rebuilds the table to drop the column. See AlterColumnType.
+func (db *DB) DropColumn(table interface{}, column string) error {
+	return db.DropColumnContext(context.Background(), table, column)
+}
+
+// DropColumnContext is like DropColumn, but with context.
+func (db *DB) DropColumnContext(ctx context.Context, table interface{}, column string) error {
+	_, m, err := db.tableValueOf("DropColumn", table)
+	if err != nil {
+		return err
+	}
+	if query := db.dialect.DropColumn(m.tableName, column); query != "" {
+		return db.execDDL(ctx, []string{query})
+	}
+	queries, err := db.rebuildTableQueries(ctx, "DropColumn", m, func(c Column, definition string) string {
+		if c.Name == column {
+			return ""
+		}
+		return definition
+	})
+	if err != nil {
+		return err
+	}
+	return db.execDDL(ctx, queries)
+}
+
+// RenameColumn renames the column from to the column of the struct field.
+// table must be struct or pointer to struct, and field is the name of the
+// struct field that has the new column name.
+// "RENAME COLUMN" requires SQLite 3.25.0 or later, or MySQL 8.0 or later.
+//
+//     db.RenameColumn(&User{}, "mail", "Email")
+func (db *DB) RenameColumn(table interface{}, from, field string) error {
+	return db.RenameColumnContext(context.Background(), table, from, field)
+}
+
+// RenameColumnContext is like RenameColumn, but with context.
+func (db *DB) RenameColumnContext(ctx context.Context, table interface{}, from, field string) error {
+	m, f, err := db.tableField("RenameColumn", table, field)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("ALTER TABLE %s RENAME COLUMN %s TO %s",
+		db.dialect.Quote(m.tableName), db.dialect.Quote(from), db.dialect.Quote(f.column))
+	return db.execDDL(ctx, []string{query})
+}
+
+// RenameTable renames the table from to the table name of the struct.
+// table must be struct or pointer to struct.
+//
+//     db.RenameTable(&Account{}, "user")
+func (db *DB) RenameTable(table interface{}, from string) error {
+	return db.RenameTableContext(context.Background(), table, from)
+}
+
+// RenameTableContext is like RenameTable, but with context.
+func (db *DB) RenameTableContext(ctx context.Context, table interface{}, from string) error {
+	_, m, err := db.tableValueOf("RenameTable", table)
+	if err != nil {
+		return err
+	}
+	query := fmt.Sprintf("ALTER TABLE %s RENAME TO %s", db.dialect.Quote(from), db.dialect.Quote(m.tableName))
+	return db.execDDL(ctx, []string{query})
+}
+
+// AlterColumnType changes the SQL type, the nullability and the default
+// value of the column to the definition of the struct field.
+// table must be struct or pointer to struct, and field is the name of the
+// struct field. The primary key column can't be altered, because its
+// auto-increment, such as the sequence of PostgreSQL, would be lost.
+//
+// SQLite3 can't alter the column, so the table will be rebuilt: a new table
+// is created with the new column definition, the rows are copied to it, and
+// the old table is replaced. The other columns, the primary key, the unique
+// constraints, the foreign keys and the indexes are copied from the old
+// table. Note that the foreign key constraints should be disabled by
+// "PRAGMA foreign_keys = OFF" while rebuilding, if the other tables
+// reference the table.
+func (db *DB) AlterColumnType(table interface{}, field string) error {
+	return db.AlterColumnTypeContext(context.Background(), table, field)
+}
+
+// AlterColumnTypeContext is like AlterColumnType, but with context.
+func (db *DB) AlterColumnTypeContext(ctx context.Context, table interface{}, field string) error {
+	m, f, err := db.tableField("AlterColumnType", table, field)
+	if err != nil {
+		return err
+	}
+	if f.pk {
+		return fmt.Errorf(`AlterColumnType: primary key column "%s" can't be altered`, f.column)
+	}
+	if f.sizeErr != nil {
+		return f.sizeErr
+	}
+	typ, allowNull := db.dialect.SQLType(reflect.Zero(f.field.Type).Interface(), false, f.size)
+	def, err := db.defaultFromTag(f)
+	if err != nil {
+		return err
+	}
+	if queries := db.dialect.AlterColumn(m.tableName, f.column, typ, allowNull, def); queries != nil {
+		return db.execDDL(ctx, queries)
+	}
+	definition, err := db.columnDefinition("AlterColumnType", m, f, false)
+	if err != nil {
+		return err
+	}
+	found := false
+	queries, err := db.rebuildTableQueries(ctx, "AlterColumnType", m, func(c Column, d string) string {
+		if c.Name == f.column {
+			found = true
+			return definition
+		}
+		return d
+	})
+	if err != nil {
+		return err
+	}
+	if !found {
+		return fmt.Errorf(`AlterColumnType: column "%s" isn't found in table "%s"`, f.column, m.tableName)
+	}
+	return db.execDDL(ctx, queries)
+}
+
+// tableField returns the model of table and the field of the struct field name.
+func (db *DB) tableField(name string, table interface{}, field string) (*model, *modelField, error) {
+	_, m, err := db.tableValueOf(name, table)
+	if err != nil {
+		return nil, nil, err
+	}
+	f := m.fieldByName(field)
+	if f == nil {
+		return nil, nil, fmt.Errorf("%s: field `%s` isn't found in %v", name, field, m.typ)
+	}
+	return m, f, nil
+}
+
+// uniqueIndexQuery returns "CREATE UNIQUE INDEX" SQL of the column of f.
+func (db *DB) uniqueIndexQuery(m *model, f *modelField) string {
+	indexName := strings.Join([]string{"index", m.tableName, f.column}, "_")
+	return fmt.Sprintf("CREATE UNIQUE INDEX %s ON %s (%s)",
+		db.dialect.Quote(indexName), db.dialect.Quote(m.tableName), db.dialect.Quote(f.column))
+}
+
+// execDDL executes the DDL queries in order.
+// They are executed in a transaction if the dialect supports the
+// transactional DDL.
+func (db *DB) execDDL(ctx context.Context, queries []string) error {
+	run := func(db *DB) error {
+		for _, query := range queries {
+			if _, err := db.execDirect(ctx, query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	if !db.dialect.SupportsTransactionalDDL() {
+		return run(db)
+	}
+	return db.TransactionContext(ctx, nil, func(tx *Tx) error {
+		return run(tx.txDB)
+	})
+}
+
+// rebuildTableQueries returns the SQLs to rebuild the table of m by copying
+// the rows to a new table, for the databases that can't alter the columns.
+// The definitions of the new table are copied from the current table.
+// change is called with each column and its current definition, and
+// returns the new definition of the column, or empty string to drop it.
+func (db *DB) rebuildTableQueries(ctx context.Context, name string, m *model, change func(c Column, definition string) string) ([]string, error) {
+	table, err := db.DescribeTableContext(ctx, m.tableName)
+	if err != nil {
+		return nil, err
+	}
+	if table == nil {
+		return nil, fmt.Errorf(`%s: table "%s" doesn't exist`, name, m.tableName)
+	}
+	quoteColumns := func(columns []string) string {
+		quoted := make([]string, len(columns))
+		for i, column := range columns {
+			quoted[i] = db.dialect.Quote(column)
+		}
+		return strings.Join(quoted, ", ")
+	}
+	exists := map[string]bool{}
+	var definitions, columns []string
+	for _, c := range table.Columns {
+		definition := change(c, db.columnDefinitionOf(table, m, c))
+		if definition == "" {
+			continue
+		}
+		exists[c.Name] = true
+		definitions = append(definitions, definition)
+		columns = append(columns, c.Name)
+	}
+	if len(columns) < 1 {
+		return nil, fmt.Errorf(`%s: table "%s" must have at least one column`, name, m.tableName)
+	}
+	covered := func(cols []string) bool {
+		for _, column := range cols {
+			if !exists[column] {
+				return false
+			}
+		}
+		return true
+	}
+	if len(table.PrimaryKey) > 1 && covered(table.PrimaryKey) {
+		definitions = append(definitions, fmt.Sprintf("PRIMARY KEY (%s)", quoteColumns(table.PrimaryKey)))
+	}
+	constraints := map[string]bool{}
+	for _, c := range table.UniqueConstraints {
+		constraints[c.Name] = true
+		if covered(c.Columns) {
+			definitions = append(definitions, fmt.Sprintf("UNIQUE (%s)", quoteColumns(c.Columns)))
+		}
+	}
+	for _, fk := range table.ForeignKeys {
+		if covered(fk.Columns) {
+			definitions = append(definitions, fmt.Sprintf("FOREIGN KEY (%s) REFERENCES %s (%s)",
+				quoteColumns(fk.Columns), db.dialect.Quote(fk.RefTable), quoteColumns(fk.RefColumns)))
+		}
+	}
+	tableName := db.dialect.Quote(m.tableName)
+	tmpName := db.dialect.Quote("_genmai_rebuild_" + m.tableName)
+	queries := []string{
+		fmt.Sprintf("CREATE TABLE %s (%s)", tmpName, strings.Join(definitions, ", ")),
+		fmt.Sprintf("INSERT INTO %s (%s) SELECT %s FROM %s", tmpName, quoteColumns(columns), quoteColumns(columns), tableName),
+		fmt.Sprintf("DROP TABLE %s", tableName),
+		fmt.Sprintf("ALTER TABLE %s RENAME TO %s", tmpName, tableName),
+	}
+	for _, index := range table.Indexes {
+		if constraints[index.Name] || !covered(index.Columns) {
+			continue
+		}
+		query := "CREATE INDEX %s ON %s (%s)"
+		if index.Unique {
+			query = "CREATE UNIQUE INDEX %s ON %s (%s)"
+		}
+		queries = append(queries, fmt.Sprintf(query, db.dialect.Quote(index.Name), tableName, quoteColumns(index.Columns)))
+	}
+	return queries, nil
+}
+
+// columnDefinitionOf returns the column definition of the column c of the
+// current table. "AUTOINCREMENT" is added if the field of the column is
+// auto-incrementable in m.
+func (db *DB) columnDefinitionOf(table *Table, m *model, c Column) string {
+	line := []string{db.dialect.Quote(c.Name), c.Type}
+	if len(table.PrimaryKey) == 1 && table.PrimaryKey[0] == c.Name {
+		line = append(line, "PRIMARY KEY")
+		if f := m.fieldByColumn(c.Name); f != nil && f.autoIncrement {
+			line = append(line, db.dialect.AutoIncrement())
+		}
+	}
+	if !c.Nullable {
+		line = append(line, "NOT NULL")
+	}
+	if c.Default != nil {
+		line = append(line, "DEFAULT", *c.Default)
+	}
+	return strings.Join(line, " ")
+}
@@ -0,0 +1,321 @@
+package genmai
+
+import (
+	"reflect"
+	"testing"
+)
+
+type alterUser struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Email *string `db:"unique"`
+	Age   int64   `default:"20"`
+}
+
+// alterUserV1 is the old version of alterUser that doesn't have the columns
+// of Email and Age.
+type alterUserV1 struct {
+	Id   int64 `db:"pk"`
+	Name string
+}
+
+func (u *alterUserV1) TableName() string {
+	return "alter_user"
+}
+
+// alterUserV2 is the old version of alterUser that has the obsolete column.
+type alterUserV2 struct {
+	Id       int64 `db:"pk"`
+	Name     string
+	Email    *string `db:"unique"`
+	Age      int64   `default:"20"`
+	Obsolete *string
+}
+
+func (u *alterUserV2) TableName() string {
+	return "alter_user"
+}
+
+// alterUserV3 is the old version of alterUser that the column of Email is
+// named "mail".
+type alterUserV3 struct {
+	Id   int64 `db:"pk"`
+	Name string
+	Mail *string `db:"unique"`
+	Age  int64   `default:"20"`
+}
+
+func (u *alterUserV3) TableName() string {
+	return "alter_user"
+}
+
+// alterUserV4 is the old version of alterUser that the type of Age is text.
+type alterUserV4 struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Email *string `db:"unique"`
+	Age   *string
+}
+
+func (u *alterUserV4) TableName() string {
+	return "alter_user"
+}
+
+// alterScoreUser is the new version of alterUserV1 that has the non-pointer
+// field.
+type alterScoreUser struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Score int64 `default:"10"`
+}
+
+func (u *alterScoreUser) TableName() string {
+	return "alter_user"
+}
+
+// alterScoreUserNoDefault is like alterScoreUser, but the non-pointer field
+// doesn't have "default" tag.
+type alterScoreUserNoDefault struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Score int64
+}
+
+func (u *alterScoreUserNoDefault) TableName() string {
+	return "alter_user"
+}
+
+// oldUser is the old version of alterUser that the table name is different.
+type oldUser alterUser
+
+func alterColumnNames(t *testing.T, db *DB, table string) []string {
+	columns, err := db.Columns(table)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range columns {
+		names = append(names, c.Name)
+	}
+	return names
+}
+
+// alterTestDB returns the DB that "alter_user" table is created by table.
+func alterTestDB(t *testing.T, table interface{}) *DB {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS alter_user`,
+		`DROP TABLE IF EXISTS old_user`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := db.CreateTable(table); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// closeAlterTestDB drops the tables that are created by alterTestDB, and
+// closes db.
+func closeAlterTestDB(db *DB) {
+	db.db.Exec(`DROP TABLE IF EXISTS alter_user`)
+	db.db.Exec(`DROP TABLE IF EXISTS old_user`)
+	db.Close()
+}
+
+func TestDB_AddColumn(t *testing.T) {
+	db := alterTestDB(t, &alterUserV1{})
+	defer closeAlterTestDB(db)
+	for _, field := range []string{"Email", "Age"} {
+		if err := db.AddColumn(&alterUser{}, field); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if actual, expect := alterColumnNames(t, db, "alter_user"), []string{"id", "name", "email", "age"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AddColumn(...) => %#v; want %#v", actual, expect)
+	}
+	columns, err := db.Columns("alter_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := columns[2].Nullable, true; actual != expect {
+		t.Errorf(`DB.AddColumn(..., "Email") => nullable %v; want %v`, actual, expect)
+	}
+	if actual, expect := columns[3].Nullable, false; actual != expect {
+		t.Errorf(`DB.AddColumn(..., "Age") => nullable %v; want %v`, actual, expect)
+	}
+	if actual, expect := columns[3].Default, "20"; actual == nil || *actual != expect {
+		t.Errorf(`DB.AddColumn(..., "Age") => default %#v; want %#v`, actual, expect)
+	}
+	indexes, err := db.Indexes("alter_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasUniqueIndex(indexes, "email") {
+		t.Errorf(`DB.AddColumn(..., "Email") => %#v; want unique index of "email"`, indexes)
+	}
+
+	for _, v := range []string{"Id", "Unknown"} {
+		if err := db.AddColumn(&alterUser{}, v); err == nil {
+			t.Errorf("DB.AddColumn(..., %#v) => nil; want error", v)
+		}
+	}
+}
+
+func TestDB_AddColumn_notNull(t *testing.T) {
+	db := alterTestDB(t, &alterUserV1{})
+	defer closeAlterTestDB(db)
+	if _, err := db.Insert(&alterUserV1{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AddColumn(&alterScoreUserNoDefault{}, "Score"); err == nil {
+		t.Errorf(`DB.AddColumn(NOT NULL without default) => nil; want error`)
+	}
+	if err := db.AddColumn(&alterScoreUser{}, "Score"); err != nil {
+		t.Fatal(err)
+	}
+	var users []alterScoreUser
+	if err := db.Select(&users); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := users, []alterScoreUser{{Id: 1, Name: "alice", Score: 10}}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AddColumn(...) => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_DropColumn(t *testing.T) {
+	db := alterTestDB(t, &alterUserV2{})
+	defer closeAlterTestDB(db)
+	if err := db.CreateIndex(&alterUserV2{}, "name"); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateIndex(&alterUserV2{}, "obsolete"); err != nil {
+		t.Fatal(err)
+	}
+	email, obsolete := "alice@example.com", "x"
+	if _, err := db.Insert(&alterUserV2{Name: "alice", Email: &email, Age: 30, Obsolete: &obsolete}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.DropColumn(&alterUser{}, "obsolete"); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := alterColumnNames(t, db, "alter_user"), []string{"id", "name", "email", "age"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.DropColumn(...) => %#v; want %#v", actual, expect)
+	}
+	var users []alterUser
+	if err := db.Select(&users); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := users, []alterUser{{Id: 1, Name: "alice", Email: &email, Age: 30}}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.DropColumn(...) => %#v; want %#v", actual, expect)
+	}
+	indexes, err := db.Indexes("alter_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, index := range indexes {
+		if index.Columns[0] == "name" {
+			names = append(names, index.Name)
+		}
+	}
+	if actual, expect := names, []string{"index_alter_user_name"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.DropColumn(...) => indexes %#v; want %#v", actual, expect)
+	}
+	if !hasUniqueIndex(indexes, "email") {
+		t.Errorf(`DB.DropColumn(...) => %#v; want unique index of "email"`, indexes)
+	}
+	if _, err := db.Insert(&alterUser{Name: "bob", Email: &email}); err == nil {
+		t.Errorf("DB.Insert(duplicated email) => nil; want error")
+	}
+}
+
+func TestDB_RenameColumn(t *testing.T) {
+	db := alterTestDB(t, &alterUserV3{})
+	defer closeAlterTestDB(db)
+	if err := db.RenameColumn(&alterUser{}, "mail", "Email"); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := alterColumnNames(t, db, "alter_user"), []string{"id", "name", "email", "age"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.RenameColumn(...) => %#v; want %#v", actual, expect)
+	}
+	if err := db.RenameColumn(&alterUser{}, "name", "Unknown"); err == nil {
+		t.Errorf(`DB.RenameColumn(..., "Unknown") => nil; want error`)
+	}
+}
+
+func TestDB_RenameTable(t *testing.T) {
+	db := alterTestDB(t, &oldUser{})
+	defer closeAlterTestDB(db)
+	if err := db.RenameTable(&alterUser{}, "old_user"); err != nil {
+		t.Fatal(err)
+	}
+	tables, err := db.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := map[string]bool{}
+	for _, table := range tables {
+		found[table] = true
+	}
+	if !found["alter_user"] || found["old_user"] {
+		t.Errorf(`DB.RenameTable(...) => %#v; want "alter_user" instead of "old_user"`, tables)
+	}
+}
+
+func TestDB_AlterColumnType(t *testing.T) {
+	db := alterTestDB(t, &alterUserV4{})
+	defer closeAlterTestDB(db)
+	if err := db.CreateIndex(&alterUserV4{}, "age"); err != nil {
+		t.Fatal(err)
+	}
+	age := "30"
+	if _, err := db.Insert(&alterUserV4{Name: "alice", Age: &age}); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.AlterColumnType(&alterUser{}, "Age"); err != nil {
+		t.Fatal(err)
+	}
+	columns, err := db.Columns("alter_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	c := columns[3]
+	typ, _ := db.dialect.SQLType(int64(0), false, 0)
+	if !sameSQLType(c.Type, typ) || c.Nullable || c.Default == nil || *c.Default != "20" {
+		t.Errorf(`DB.AlterColumnType(..., "Age") => %#v; want %s NOT NULL DEFAULT 20`, c, typ)
+	}
+	var users []alterUser
+	if err := db.Select(&users); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := users, []alterUser{{Id: 1, Name: "alice", Age: 30}}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AlterColumnType(...) => %#v; want %#v", actual, expect)
+	}
+	indexes, err := db.Indexes("alter_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := false
+	for _, index := range indexes {
+		if index.Name == "index_alter_user_age" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf(`DB.AlterColumnType(...) => %#v; want index "index_alter_user_age"`, indexes)
+	}
+	if _, err := db.Insert(&alterUser{Name: "bob"}); err != nil {
+		t.Fatal(err)
+	}
+	for _, v := range []string{"Id", "Unknown"} {
+		if err := db.AlterColumnType(&alterUser{}, v); err == nil {
+			t.Errorf("DB.AlterColumnType(..., %#v) => nil; want error", v)
+		}
+	}
+}
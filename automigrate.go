@@ -0,0 +1,189 @@
+package genmai
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+)
+
+// AutoMigrateOptions represents the options of AutoMigrate.
+type AutoMigrateOptions struct {
+	// If DropColumns is true, the columns that aren't defined in the struct
+	// will be dropped.
+	DropColumns bool
+
+	// If DryRun is true, AutoMigrate returns the SQLs without executing them.
+	DryRun bool
+}
+
+// AutoMigrate changes the table in the database to match the struct.
+// table must be struct or pointer to struct.
+// It compares the columns of the table with the columns that CreateTable
+// would create, and runs the following SQLs in order:
+//
+//     1. "CREATE TABLE" if the table doesn't exist.
+//     2. "ALTER TABLE ... ADD COLUMN" for the missing columns.
+//     3. "ALTER TABLE ..." for the columns that the SQL type or the nullability is changed.
+//     4. "CREATE UNIQUE INDEX" for the "unique" fields that don't have a unique index.
+//     5. "ALTER TABLE ... DROP COLUMN" for the columns that aren't in the struct, if DropColumns of opts is true.
+//
+// If the dialect can't alter or drop the columns, such as SQLite3, 3. and 5.
+// are done by rebuilding the table before 2. See AlterColumnType.
+// The SQLs are run in a transaction if the dialect supports the transactional
+// DDL. The indexes that are created by CreateIndex are left as is, because
+// they aren't defined in the struct.
+// The missing NOT NULL columns must have "default" tag to fill the existing
+// rows. opts can be nil. AutoMigrate returns the SQLs that are run.
+//
+//     queries, err := db.AutoMigrate(&User{}, &genmai.AutoMigrateOptions{DryRun: true})
+func (db *DB) AutoMigrate(table interface{}, opts *AutoMigrateOptions) (queries []string, err error) {
+	return db.AutoMigrateContext(context.Background(), table, opts)
+}
+
+// AutoMigrateContext is like AutoMigrate, but with context.
+func (db *DB) AutoMigrateContext(ctx context.Context, table interface{}, opts *AutoMigrateOptions) (queries []string, err error) {
+	_, m, err := db.tableValueOf("AutoMigrate", table)
+	if err != nil {
+		return nil, err
+	}
+	if opts == nil {
+		opts = &AutoMigrateOptions{}
+	}
+	if queries, err = db.autoMigrateQueries(ctx, m, opts); err != nil {
+		return nil, err
+	}
+	if opts.DryRun || len(queries) < 1 {
+		return queries, nil
+	}
+	return queries, db.execDDL(ctx, queries)
+}
+
+// autoMigrateQueries returns the SQLs to change the table of m to match m.
+func (db *DB) autoMigrateQueries(ctx context.Context, m *model, opts *AutoMigrateOptions) (queries []string, err error) {
+	columns, err := db.ColumnsContext(ctx, m.tableName)
+	if err != nil {
+		return nil, err
+	}
+	if len(columns) < 1 {
+		query, err := db.createTableQuery(m, false)
+		if err != nil {
+			return nil, err
+		}
+		return []string{query}, nil
+	}
+	existing := make(map[string]Column, len(columns))
+	for _, c := range columns {
+		existing[c.Name] = c
+	}
+	tableName := db.dialect.Quote(m.tableName)
+	var adds, alters, drops []string
+	// rebuild is true if the dialect can't alter or drop some columns.
+	rebuild := false
+	definitions := map[string]string{}
+	for _, f := range m.fields {
+		c, ok := existing[f.column]
+		if !ok {
+			definition, err := db.addColumnDefinition("AutoMigrate", m, f)
+			if err != nil {
+				return nil, err
+			}
+			adds = append(adds, fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s", tableName, definition))
+			continue
+		}
+		if f.sizeErr != nil {
+			return nil, f.sizeErr
+		}
+		typ, allowNull := db.dialect.SQLType(reflect.Zero(f.field.Type).Interface(), f.autoIncrement, f.size)
+		if sameSQLType(typ, c.Type) && (f.pk || allowNull == c.Nullable) {
+			continue
+		}
+		if f.pk {
+			return nil, fmt.Errorf(`AutoMigrate: primary key column "%s" can't be altered`, f.column)
+		}
+		typ, _ = db.dialect.SQLType(reflect.Zero(f.field.Type).Interface(), false, f.size)
+		def, err := db.defaultFromTag(f)
+		if err != nil {
+			return nil, err
+		}
+		if definitions[f.column], err = db.columnDefinition("AutoMigrate", m, f, false); err != nil {
+			return nil, err
+		}
+		qs := db.dialect.AlterColumn(m.tableName, f.column, typ, allowNull, def)
+		if qs == nil {
+			rebuild = true
+		}
+		alters = append(alters, qs...)
+	}
+	dropped := map[string]bool{}
+	if opts.DropColumns {
+		for _, c := range columns {
+			if m.fieldByColumn(c.Name) != nil {
+				continue
+			}
+			dropped[c.Name] = true
+			query := db.dialect.DropColumn(m.tableName, c.Name)
+			if query == "" {
+				rebuild = true
+			}
+			drops = append(drops, query)
+		}
+	}
+	indexes, err := db.IndexesContext(ctx, m.tableName)
+	if err != nil {
+		return nil, err
+	}
+	var uniques []string
+	for _, f := range m.fields {
+		if !f.unique || f.pk || hasUniqueIndex(indexes, f.column) {
+			continue
+		}
+		uniques = append(uniques, db.uniqueIndexQuery(m, f))
+	}
+	if !rebuild {
+		queries = append(append(append(adds, alters...), uniques...), drops...)
+		return queries, nil
+	}
+	// the table is rebuilt from the current table with all of the changes
+	// and the drops, so it must be done before the columns are added.
+	if queries, err = db.rebuildTableQueries(ctx, "AutoMigrate", m, func(c Column, definition string) string {
+		if dropped[c.Name] {
+			return ""
+		}
+		if d, ok := definitions[c.Name]; ok {
+			return d
+		}
+		return definition
+	}); err != nil {
+		return nil, err
+	}
+	queries = append(append(queries, adds...), uniques...)
+	return queries, nil
+}
+
+// hasUniqueIndex returns whether indexes has the unique index of only column.
+func hasUniqueIndex(indexes []Index, column string) bool {
+	for _, index := range indexes {
+		if index.Unique && len(index.Columns) == 1 && index.Columns[0] == column {
+			return true
+		}
+	}
+	return false
+}
+
+// addColumnDefinition returns the definition of the column of f for
+// "ALTER TABLE ... ADD COLUMN".
+// The NOT NULL column must have "default" tag, because the existing rows
+// can't be filled without the default value.
+func (db *DB) addColumnDefinition(name string, m *model, f *modelField) (string, error) {
+	if f.pk {
+		return "", fmt.Errorf(`%s: primary key column "%s" can't be added`, name, f.column)
+	}
+	definition, err := db.columnDefinition(name, m, f, false)
+	if err != nil {
+		return "", err
+	}
+	if _, allowNull := db.dialect.SQLType(reflect.Zero(f.field.Type).Interface(), false, f.size); !allowNull && f.def == "" {
+		return "", fmt.Errorf(`%s: NOT NULL column "%s" can't be added without "default" tag: use a pointer type or specify "default" tag`, name, f.column)
+	}
+	return definition, nil
+}
@@ -0,0 +1,233 @@
+package genmai
+
+import (
+	"reflect"
+	"testing"
+)
+
+type autoMigrateUser struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Email *string `db:"unique"`
+	Age   *int64
+}
+
+// autoMigrateUserV1 is the old version of autoMigrateUser.
+type autoMigrateUserV1 struct {
+	Id       int64 `db:"pk"`
+	Name     string
+	Obsolete *string
+}
+
+func (u *autoMigrateUserV1) TableName() string {
+	return "auto_migrate_user"
+}
+
+// autoMigrateUserV2 is the old version of autoMigrateUser that the type and
+// the nullability of the columns are different.
+type autoMigrateUserV2 struct {
+	Id    int64 `db:"pk"`
+	Name  *string
+	Email *string
+	Age   *int32
+}
+
+func (u *autoMigrateUserV2) TableName() string {
+	return "auto_migrate_user"
+}
+
+// autoMigrateUserV3 is the new version of autoMigrateUser that has the
+// non-pointer field.
+type autoMigrateUserV3 struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Email *string `db:"unique"`
+	Age   *int64
+	Score int64 `default:"10"`
+}
+
+func (u *autoMigrateUserV3) TableName() string {
+	return "auto_migrate_user"
+}
+
+// autoMigrateUserV4 is like autoMigrateUserV3, but the non-pointer field
+// doesn't have "default" tag.
+type autoMigrateUserV4 struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Email *string `db:"unique"`
+	Age   *int64
+	Score int64
+}
+
+func (u *autoMigrateUserV4) TableName() string {
+	return "auto_migrate_user"
+}
+
+func autoMigrateColumns(t *testing.T, db *DB) map[string]Column {
+	columns, err := db.Columns("auto_migrate_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	result := make(map[string]Column, len(columns))
+	for _, c := range columns {
+		result[c.Name] = c
+	}
+	return result
+}
+
+func TestDB_AutoMigrate(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS auto_migrate_user`); err != nil {
+		t.Fatal(err)
+	}
+	defer db.db.Exec(`DROP TABLE IF EXISTS auto_migrate_user`)
+	actual, err := db.AutoMigrate(&autoMigrateUser{}, &AutoMigrateOptions{DryRun: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	query, err := db.createTableQuery(db.modelOf(reflect.TypeOf(autoMigrateUser{})), false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string{query}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AutoMigrate(DryRun) => %#v; want %#v", actual, expect)
+	}
+	if columns := autoMigrateColumns(t, db); len(columns) != 0 {
+		t.Errorf("DB.AutoMigrate(DryRun) => table is created; want not created")
+	}
+
+	if err := db.CreateTable(&autoMigrateUserV1{}); err != nil {
+		t.Fatal(err)
+	}
+	opts := &AutoMigrateOptions{DropColumns: true, DryRun: true}
+	if actual, err = db.AutoMigrate(&autoMigrateUser{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if _, ok := db.dialect.(*SQLite3Dialect); ok {
+		expect := []string{
+			`CREATE TABLE "_genmai_rebuild_auto_migrate_user" ("id" INTEGER PRIMARY KEY AUTOINCREMENT NOT NULL, "name" TEXT NOT NULL)`,
+			`INSERT INTO "_genmai_rebuild_auto_migrate_user" ("id", "name") SELECT "id", "name" FROM "auto_migrate_user"`,
+			`DROP TABLE "auto_migrate_user"`,
+			`ALTER TABLE "_genmai_rebuild_auto_migrate_user" RENAME TO "auto_migrate_user"`,
+			`ALTER TABLE "auto_migrate_user" ADD COLUMN "email" text`,
+			`ALTER TABLE "auto_migrate_user" ADD COLUMN "age" integer`,
+			`CREATE UNIQUE INDEX "index_auto_migrate_user_email" ON "auto_migrate_user" ("email")`,
+		}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("DB.AutoMigrate(DropColumns) => %#v; want %#v", actual, expect)
+		}
+	}
+	expect := actual
+	opts.DryRun = false
+	if actual, err = db.AutoMigrate(&autoMigrateUser{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AutoMigrate(DropColumns) => %#v; want %#v", actual, expect)
+	}
+	columns, err := db.Columns("auto_migrate_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, c := range columns {
+		names = append(names, c.Name)
+	}
+	if actual, expect := names, []string{"id", "name", "email", "age"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Columns(...) => %#v; want %#v", actual, expect)
+	}
+	if actual, err = db.AutoMigrate(&autoMigrateUser{}, opts); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string(nil); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AutoMigrate(migrated) => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_AutoMigrate_alterColumn(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS auto_migrate_user`); err != nil {
+		t.Fatal(err)
+	}
+	defer db.db.Exec(`DROP TABLE IF EXISTS auto_migrate_user`)
+	if err := db.CreateTable(&autoMigrateUserV2{}); err != nil {
+		t.Fatal(err)
+	}
+	name, age := "alice", int32(30)
+	if _, err := db.Insert(&autoMigrateUserV2{Name: &name, Age: &age}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AutoMigrate(&autoMigrateUser{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	columns := autoMigrateColumns(t, db)
+	if actual, expect := columns["name"].Nullable, false; actual != expect {
+		t.Errorf(`DB.AutoMigrate(...) => nullable of "name" %v; want %v`, actual, expect)
+	}
+	typ, _ := db.dialect.SQLType(int64(0), false, 0)
+	if actual, expect := columns["age"].Type, typ; !sameSQLType(actual, expect) {
+		t.Errorf(`DB.AutoMigrate(...) => type of "age" %#v; want %#v`, actual, expect)
+	}
+	var users []autoMigrateUser
+	if err := db.Select(&users); err != nil {
+		t.Fatal(err)
+	}
+	n := int64(30)
+	if actual, expect := users, []autoMigrateUser{{Id: 1, Name: "alice", Age: &n}}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AutoMigrate(...) => %#v; want %#v", actual, expect)
+	}
+	indexes, err := db.Indexes("auto_migrate_user")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !hasUniqueIndex(indexes, "email") {
+		t.Errorf(`DB.AutoMigrate(...) => %#v; want unique index of "email"`, indexes)
+	}
+	queries, err := db.AutoMigrate(&autoMigrateUser{}, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if expect := []string(nil); !reflect.DeepEqual(queries, expect) {
+		t.Errorf("DB.AutoMigrate(migrated) => %#v; want %#v", queries, expect)
+	}
+}
+
+func TestDB_AutoMigrate_addNotNullColumn(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS auto_migrate_user`); err != nil {
+		t.Fatal(err)
+	}
+	defer db.db.Exec(`DROP TABLE IF EXISTS auto_migrate_user`)
+	if err := db.CreateTable(&autoMigrateUser{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Insert(&autoMigrateUser{Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.AutoMigrate(&autoMigrateUserV4{}, nil); err == nil {
+		t.Errorf("DB.AutoMigrate(NOT NULL without default) => nil; want error")
+	}
+	if _, err := db.AutoMigrate(&autoMigrateUserV3{}, nil); err != nil {
+		t.Fatal(err)
+	}
+	var users []autoMigrateUserV3
+	if err := db.Select(&users); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := users, []autoMigrateUserV3{{Id: 1, Name: "alice", Score: 10}}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.AutoMigrate(...) => %#v; want %#v", actual, expect)
+	}
+}
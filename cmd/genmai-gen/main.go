@@ -0,0 +1,84 @@
+// Command genmai-gen generates the Go structs for genmai from the schema of
+// an existing database.
+//
+// Usage:
+//
+//     genmai-gen -dialect DIALECT -dsn DSN [-package NAME] [-tables TABLES] [-null-types] [-o FILE]
+//
+// DIALECT is one of "sqlite3", "mysql" and "postgres".
+// TABLES is the comma-separated table names. If it isn't given, the structs
+// of all tables will be generated.
+//
+// Example:
+//
+//     genmai-gen -dialect postgres -dsn "user=postgres dbname=app sslmode=disable" -package models -o models/models.go
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"strings"
+
+	_ "github.com/go-sql-driver/mysql"
+	_ "github.com/lib/pq"
+	_ "github.com/mattn/go-sqlite3"
+	"github.com/naoina/genmai"
+)
+
+var dialects = map[string]genmai.Dialect{
+	"sqlite3":  &genmai.SQLite3Dialect{},
+	"mysql":    &genmai.MySQLDialect{},
+	"postgres": &genmai.PostgresDialect{},
+}
+
+func main() {
+	var (
+		dialect   = flag.String("dialect", "", `dialect of the database. "sqlite3", "mysql" or "postgres"`)
+		dsn       = flag.String("dsn", "", "data source name of the database")
+		pkg       = flag.String("package", "models", "package name of the generated code")
+		tables    = flag.String("tables", "", "comma-separated table names. all tables if empty")
+		nullTypes = flag.Bool("null-types", false, "use sql.Null* types for the nullable columns instead of pointers")
+		output    = flag.String("o", "", "output file. stdout if empty")
+	)
+	flag.Parse()
+	if err := run(*dialect, *dsn, *pkg, *tables, *nullTypes, *output); err != nil {
+		fmt.Fprintf(os.Stderr, "genmai-gen: %v\n", err)
+		os.Exit(1)
+	}
+}
+
+func run(dialect, dsn, pkg, tables string, nullTypes bool, output string) error {
+	d, ok := dialects[dialect]
+	if !ok {
+		return fmt.Errorf("unknown dialect %q", dialect)
+	}
+	if dsn == "" {
+		return fmt.Errorf("-dsn must be specified")
+	}
+	db, err := genmai.New(d, dsn)
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+	opts := &genmai.GenerateOptions{
+		Package:   pkg,
+		NullTypes: nullTypes,
+	}
+	if tables != "" {
+		for _, table := range strings.Split(tables, ",") {
+			opts.Tables = append(opts.Tables, strings.TrimSpace(table))
+		}
+	}
+	var buf bytes.Buffer
+	if err := db.Generate(&buf, opts); err != nil {
+		return err
+	}
+	if output == "" {
+		_, err := os.Stdout.Write(buf.Bytes())
+		return err
+	}
+	return ioutil.WriteFile(output, buf.Bytes(), 0644)
+}
@@ -0,0 +1,95 @@
+package genmai
+
+import (
+	"context"
+	"reflect"
+)
+
+// CopyFrom loads one or more records to the database table by the bulk
+// loading protocol of the database. e.g. "COPY ... FROM STDIN" of PostgreSQL.
+// The obj must be pointer to struct or slice of struct, and the columns will
+// be determined as well as Insert. It is much faster than Insert for a large
+// number of records, but the auto-incremented IDs won't be set to obj.
+// The records will be loaded in a transaction unless the DB is already bound
+// to a transaction.
+// If the dialect doesn't support the bulk loading, it falls back to Insert,
+// that splits the records into several statements.
+// CopyFrom returns the number of loaded rows.
+func (db *DB) CopyFrom(obj interface{}) (affected int64, err error) {
+	return db.CopyFromContext(context.Background(), obj)
+}
+
+// CopyFromContext is like CopyFrom, but with context.
+func (db *DB) CopyFromContext(ctx context.Context, obj interface{}) (affected int64, err error) {
+	objs, m, err := db.tableObjs("CopyFrom", obj)
+	if err != nil {
+		return -1, err
+	}
+	if len(objs) < 1 {
+		return 0, nil
+	}
+	columns := make([]string, len(m.valueFields))
+	for i, f := range m.valueFields {
+		columns[i] = f.column
+	}
+	query := db.dialect.CopyFrom(m.tableName, columns)
+	if query == "" {
+		return db.InsertContext(ctx, obj)
+	}
+	for _, obj := range objs {
+		if hook, ok := obj.(BeforeInserter); ok {
+			if err := hook.BeforeInsert(); err != nil {
+				return -1, err
+			}
+		}
+	}
+	if db.inTx() {
+		affected, err = db.copyFrom(ctx, query, m, objs)
+	} else {
+		err = db.TransactionContext(ctx, nil, func(tx *Tx) (err error) {
+			affected, err = tx.copyFrom(ctx, query, m, objs)
+			return err
+		})
+	}
+	if err != nil {
+		return affected, err
+	}
+	for _, obj := range objs {
+		if hook, ok := obj.(AfterInserter); ok {
+			if err := hook.AfterInsert(); err != nil {
+				return affected, err
+			}
+		}
+	}
+	return affected, nil
+}
+
+// copyFrom sends objs by the query of Dialect.CopyFrom.
+// The DB must be bound to a transaction.
+// The statement is prepared without the statement cache because it has the
+// state of the loading.
+func (db *DB) copyFrom(ctx context.Context, query string, m *model, objs []interface{}) (affected int64, err error) {
+	defer db.logger.Print(now(), query)
+	stmt, err := db.queryer().PrepareContext(ctx, query)
+	if err != nil {
+		return -1, err
+	}
+	defer stmt.Close()
+	args := make([]interface{}, len(m.valueFields))
+	for _, obj := range objs {
+		rv := reflect.Indirect(reflect.ValueOf(obj))
+		for i, f := range m.valueFields {
+			args[i] = rv.FieldByIndex(f.index).Interface()
+		}
+		if _, err := stmt.ExecContext(ctx, args...); err != nil {
+			return -1, err
+		}
+	}
+	// flush the buffered rows and finish the loading.
+	result, err := stmt.ExecContext(ctx)
+	if err != nil {
+		return -1, err
+	}
+	affected, _ = result.RowsAffected()
+	return affected, nil
+}
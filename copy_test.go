@@ -0,0 +1,32 @@
+package genmai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDB_CopyFrom(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	if _, err := db.db.Exec(`DELETE FROM test_model`); err != nil {
+		t.Fatal(err)
+	}
+	objs := make([]testModel, 1000)
+	for i := range objs {
+		objs[i] = testModel{Id: int64(i + 1), Name: "name", Addr: "addr"}
+	}
+	n, err := db.CopyFrom(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(len(objs)); actual != expect {
+		t.Errorf("DB.CopyFrom(...) => %v; want %v", actual, expect)
+	}
+	var actual []testModel
+	if err := db.Select(&actual, db.OrderBy("id", ASC)); err != nil {
+		t.Fatal(err)
+	}
+	if expect := objs; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(...) => %d rows; want %d rows", len(actual), len(expect))
+	}
+}
@@ -4,6 +4,7 @@ import (
 	"database/sql"
 	"errors"
 	"fmt"
+	"hash/fnv"
 	"strings"
 	"time"
 )
@@ -36,8 +37,139 @@ type Dialect interface {
 
 	// LastInsertId returns an SQL to get the last inserted id.
 	LastInsertId() string
+
+	// IsRetryableError returns whether the err is caused by a serialization
+	// failure or a deadlock, and the transaction can be retried.
+	IsRetryableError(err error) bool
+
+	// SavePoint returns an SQL to create a savepoint named name.
+	SavePoint(name string) string
+
+	// ReleaseSavePoint returns an SQL to release the savepoint named name.
+	ReleaseSavePoint(name string) string
+
+	// RollbackToSavePoint returns an SQL to rollback to the savepoint named name.
+	RollbackToSavePoint(name string) string
+
+	// SupportsRowValueIn returns whether the database supports the row value
+	// constructor in the IN predicate. e.g. (a, b) IN ((1, 2), (3, 4))
+	SupportsRowValueIn() bool
+
+	// OnConflict returns the clause that is appended to the "INSERT" query to
+	// update the columns of update on conflict of the columns of conflict.
+	// If update is empty, the conflicted rows must be left as is.
+	OnConflict(conflict, update []string) string
+
+	// BulkInsertIDStrategy returns the way to get the auto-incremented IDs
+	// of the rows inserted by a bulk insert.
+	BulkInsertIDStrategy() InsertIDStrategy
+
+	// Returning returns the "RETURNING" clause of columns that is appended
+	// to "INSERT", "UPDATE" and "DELETE" queries.
+	// If the database doesn't support it, it must return empty string.
+	Returning(columns []string) string
+
+	// MaxParameters returns the maximum number of parameters of a statement.
+	// The bulk insert will be split into several statements to not exceed it.
+	// If it returns 0, the number of parameters isn't limited.
+	MaxParameters() int
+
+	// CopyFrom returns an SQL to start the bulk loading of columns into the
+	// table. The rows will be sent by Exec of the prepared statement of the
+	// SQL, and it will be finished by Exec without arguments.
+	// If the database/sql driver doesn't support it, it must return empty string.
+	CopyFrom(table string, columns []string) string
+
+	// SupportsTransactionalDDL returns whether the DDL statements can be
+	// rolled back in a transaction.
+	SupportsTransactionalDDL() bool
+
+	// AdvisoryLock returns an SQL and its arguments to acquire the
+	// session-level lock named name.
+	// The SQL must wait until the lock is acquired, and return a row of a
+	// true value (e.g. 1) if it's acquired.
+	// If the database doesn't support it, it must return empty string.
+	AdvisoryLock(name string) (string, []interface{})
+
+	// AdvisoryUnlock returns an SQL and its arguments to release the lock
+	// that is acquired by the SQL of AdvisoryLock. The SQL must return a row
+	// of a true value if it's released.
+	AdvisoryUnlock(name string) (string, []interface{})
+
+	// Columns returns an SQL and its arguments to get the columns of table in
+	// order of definition. The SQL must return the rows of the column name,
+	// the SQL type, whether it's nullable and the default value (NULL if it
+	// isn't defined).
+	// The names, such as table, must be passed as the arguments rather than
+	// be embedded in the SQL. The same applies to the following methods.
+	Columns(table string) (string, []interface{})
+
+	// Tables returns an SQL to get the names of the tables in the current
+	// database (or schema) in order of the name.
+	Tables() string
+
+	// PrimaryKey returns an SQL and its arguments to get the column names of
+	// the primary key of table in order of the position in the key.
+	PrimaryKey(table string) (string, []interface{})
+
+	// Indexes returns an SQL and its arguments to get the indexes of table
+	// excluding the primary key. The SQL must return the rows of the index
+	// name, whether it's unique and the column name, in order of the index
+	// name and the position of the column in the index.
+	Indexes(table string) (string, []interface{})
+
+	// UniqueConstraints returns an SQL and its arguments to get the unique
+	// constraints of table. The SQL must return the rows of the constraint
+	// name and the column name, in order of the constraint name and the
+	// position of the column in the constraint.
+	UniqueConstraints(table string) (string, []interface{})
+
+	// ForeignKeys returns an SQL and its arguments to get the foreign keys of
+	// table. The SQL must return the rows of the constraint name, the column
+	// name, the referenced table name and the referenced column name, in
+	// order of the constraint name and the position of the column in the
+	// constraint.
+	ForeignKeys(table string) (string, []interface{})
+
+	// DropColumn returns an SQL to drop column from table.
+	// If the database can't drop the column, it must return empty string.
+	DropColumn(table, column string) string
+
+	// AlterColumn returns SQLs to change the column of table to the SQL type
+	// typ, the nullability and the default value def. def is "DEFAULT ..."
+	// clause, or empty string if the default value isn't defined.
+	// If the database can't alter the column, it must return nil.
+	AlterColumn(table, column, typ string, allowNull bool, def string) []string
+
+	// StartTransaction returns SQLs to start a transaction with opts.
+	// The returned SQLs will be executed in order on the same connection.
+	// If it returns nil, the transaction will be started by the database/sql
+	// driver with opts. opts may be nil.
+	StartTransaction(opts *sql.TxOptions) ([]string, error)
 }
 
+// InsertIDStrategy represents the way to get the auto-incremented IDs of
+// the rows inserted by a bulk insert.
+type InsertIDStrategy int
+
+const (
+	// InsertIDReturning gets the IDs by "RETURNING" clause.
+	InsertIDReturning InsertIDStrategy = iota
+
+	// InsertIDFirstPlusOffset calculates the IDs from the last insert id
+	// and the row offset. The last insert id must be the ID of the first
+	// inserted row, and the IDs of a statement must be consecutive.
+	InsertIDFirstPlusOffset
+
+	// InsertIDLastMinusOffset is like InsertIDFirstPlusOffset, but the last
+	// insert id must be the ID of the last inserted row.
+	InsertIDLastMinusOffset
+
+	// InsertIDRowByRow inserts the rows one by one in a transaction, and
+	// gets the last insert id of each row.
+	InsertIDRowByRow
+)
+
 var (
 	ErrUsingFloatType = errors.New("float types have a rounding error problem.\n" +
 		"Please use `genmai.Rat` if you want an exact value.\n" +
@@ -125,6 +257,154 @@ func (d *SQLite3Dialect) LastInsertId() string {
 	return `SELECT last_insert_rowid()`
 }
 
+// IsRetryableError returns whether the err is SQLITE_BUSY or SQLITE_LOCKED.
+func (d *SQLite3Dialect) IsRetryableError(err error) bool {
+	code, ok := driverErrorField(err, "Code")
+	if !ok {
+		return false
+	}
+	switch code {
+	case int64(5), int64(6): // SQLITE_BUSY, SQLITE_LOCKED.
+		return true
+	}
+	return false
+}
+
+// SavePoint returns an SQL to create a savepoint named name.
+func (d *SQLite3Dialect) SavePoint(name string) string {
+	return fmt.Sprintf("SAVEPOINT %s", d.Quote(name))
+}
+
+// ReleaseSavePoint returns an SQL to release the savepoint named name.
+func (d *SQLite3Dialect) ReleaseSavePoint(name string) string {
+	return fmt.Sprintf("RELEASE SAVEPOINT %s", d.Quote(name))
+}
+
+// RollbackToSavePoint returns an SQL to rollback to the savepoint named name.
+func (d *SQLite3Dialect) RollbackToSavePoint(name string) string {
+	return fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", d.Quote(name))
+}
+
+// SupportsRowValueIn returns whether the row value constructor can be used in IN predicate.
+// SQLite3 supports the row values only in the subquery of IN predicate.
+func (d *SQLite3Dialect) SupportsRowValueIn() bool {
+	return false
+}
+
+// OnConflict returns "ON CONFLICT ... DO UPDATE" or "ON CONFLICT ... DO NOTHING" clause.
+// It requires SQLite 3.24.0 or later.
+func (d *SQLite3Dialect) OnConflict(conflict, update []string) string {
+	return onConflict(d, conflict, update)
+}
+
+// BulkInsertIDStrategy returns InsertIDLastMinusOffset, because the last
+// insert rowid of SQLite3 is the ID of the last inserted row, and a statement
+// holds the write lock of the database.
+func (d *SQLite3Dialect) BulkInsertIDStrategy() InsertIDStrategy {
+	return InsertIDLastMinusOffset
+}
+
+// Returning returns "RETURNING" clause.
+// It requires SQLite 3.35.0 or later.
+func (d *SQLite3Dialect) Returning(columns []string) string {
+	return returning(d, columns)
+}
+
+// MaxParameters returns 999, that is the default value of
+// SQLITE_MAX_VARIABLE_NUMBER before SQLite 3.32.0.
+func (d *SQLite3Dialect) MaxParameters() int {
+	return 999
+}
+
+// CopyFrom always returns empty string because SQLite3 doesn't support the
+// bulk loading.
+func (d *SQLite3Dialect) CopyFrom(table string, columns []string) string {
+	return ""
+}
+
+// SupportsTransactionalDDL returns true because SQLite3 can rollback the DDL.
+func (d *SQLite3Dialect) SupportsTransactionalDDL() bool {
+	return true
+}
+
+// AdvisoryLock always returns empty string because SQLite3 doesn't support
+// the advisory lock.
+func (d *SQLite3Dialect) AdvisoryLock(name string) (string, []interface{}) {
+	return "", nil
+}
+
+// AdvisoryUnlock always returns empty string because SQLite3 doesn't support
+// the advisory lock.
+func (d *SQLite3Dialect) AdvisoryUnlock(name string) (string, []interface{}) {
+	return "", nil
+}
+
+// Tables returns the SQL that uses "sqlite_master".
+func (d *SQLite3Dialect) Tables() string {
+	return `SELECT "name" FROM "sqlite_master" WHERE "type" = 'table' AND "name" NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY "name"`
+}
+
+// PrimaryKey returns the SQL that uses "PRAGMA table_info".
+func (d *SQLite3Dialect) PrimaryKey(table string) (string, []interface{}) {
+	return fmt.Sprintf(`SELECT "name" FROM pragma_table_info(%s) WHERE "pk" > 0 ORDER BY "pk"`, d.PlaceHolder(0)), []interface{}{table}
+}
+
+// Columns returns the SQL that uses "PRAGMA table_info".
+func (d *SQLite3Dialect) Columns(table string) (string, []interface{}) {
+	return fmt.Sprintf(`SELECT "name", "type", "notnull" = 0, "dflt_value" FROM pragma_table_info(%s) ORDER BY "cid"`, d.PlaceHolder(0)), []interface{}{table}
+}
+
+// Indexes returns the SQL that uses "PRAGMA index_list" and "PRAGMA index_info".
+func (d *SQLite3Dialect) Indexes(table string) (string, []interface{}) {
+	return fmt.Sprintf(`SELECT il."name", il."unique", ii."name" FROM pragma_index_list(%s) AS il, pragma_index_info(il."name") AS ii WHERE il."origin" <> 'pk' ORDER BY il."name", ii."seqno"`, d.PlaceHolder(0)), []interface{}{table}
+}
+
+// UniqueConstraints returns the SQL that uses "PRAGMA index_list" and
+// "PRAGMA index_info". The unique constraints are the indexes that are
+// created by "UNIQUE" of "CREATE TABLE".
+func (d *SQLite3Dialect) UniqueConstraints(table string) (string, []interface{}) {
+	return fmt.Sprintf(`SELECT il."name", ii."name" FROM pragma_index_list(%s) AS il, pragma_index_info(il."name") AS ii WHERE il."origin" = 'u' ORDER BY il."name", ii."seqno"`, d.PlaceHolder(0)), []interface{}{table}
+}
+
+// ForeignKeys returns the SQL that uses "PRAGMA foreign_key_list".
+// SQLite3 doesn't keep the name of the foreign key constraints, so the
+// sequential id of the foreign key is used as the name.
+func (d *SQLite3Dialect) ForeignKeys(table string) (string, []interface{}) {
+	return fmt.Sprintf(`SELECT CAST("id" AS text), "from", "table", "to" FROM pragma_foreign_key_list(%s) ORDER BY "id", "seq"`, d.PlaceHolder(0)), []interface{}{table}
+}
+
+// DropColumn always returns empty string because "DROP COLUMN" of SQLite3
+// can't drop the columns that have the constraints or the indexes.
+func (d *SQLite3Dialect) DropColumn(table, column string) string {
+	return ""
+}
+
+// AlterColumn always returns nil because SQLite3 can't alter the column.
+func (d *SQLite3Dialect) AlterColumn(table, column, typ string, allowNull bool, def string) []string {
+	return nil
+}
+
+// StartTransaction returns "BEGIN IMMEDIATE" if the isolation level of opts is
+// sql.LevelSerializable, or "BEGIN EXCLUSIVE" if it is sql.LevelLinearizable.
+// Otherwise it returns nil because all transactions of SQLite3 are serializable.
+// It returns an error if the read-only mode is specified, because SQLite3
+// doesn't support the read-only transaction.
+func (d *SQLite3Dialect) StartTransaction(opts *sql.TxOptions) ([]string, error) {
+	if opts == nil {
+		return nil, nil
+	}
+	if opts.ReadOnly {
+		return nil, fmt.Errorf("SQLite3Dialect: read-only transaction isn't supported")
+	}
+	switch opts.Isolation {
+	case sql.LevelSerializable:
+		return []string{"BEGIN IMMEDIATE"}, nil
+	case sql.LevelLinearizable:
+		return []string{"BEGIN EXCLUSIVE"}, nil
+	}
+	return nil, nil
+}
+
 // MySQLDialect represents a dialect of the MySQL.
 // It implements the Dialect interface.
 type MySQLDialect struct{}
@@ -214,6 +494,159 @@ func (d *MySQLDialect) LastInsertId() string {
 	return `SELECT LAST_INSERT_ID()`
 }
 
+// IsRetryableError returns whether the err is ER_LOCK_DEADLOCK or ER_LOCK_WAIT_TIMEOUT.
+func (d *MySQLDialect) IsRetryableError(err error) bool {
+	number, ok := driverErrorField(err, "Number")
+	if !ok {
+		return false
+	}
+	switch number {
+	case uint64(1213), uint64(1205): // ER_LOCK_DEADLOCK, ER_LOCK_WAIT_TIMEOUT.
+		return true
+	}
+	return false
+}
+
+// SavePoint returns an SQL to create a savepoint named name.
+func (d *MySQLDialect) SavePoint(name string) string {
+	return fmt.Sprintf("SAVEPOINT %s", d.Quote(name))
+}
+
+// ReleaseSavePoint returns an SQL to release the savepoint named name.
+func (d *MySQLDialect) ReleaseSavePoint(name string) string {
+	return fmt.Sprintf("RELEASE SAVEPOINT %s", d.Quote(name))
+}
+
+// RollbackToSavePoint returns an SQL to rollback to the savepoint named name.
+func (d *MySQLDialect) RollbackToSavePoint(name string) string {
+	return fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", d.Quote(name))
+}
+
+// SupportsRowValueIn returns whether the row value constructor can be used in IN predicate.
+func (d *MySQLDialect) SupportsRowValueIn() bool {
+	return true
+}
+
+// OnConflict returns "ON DUPLICATE KEY UPDATE" clause.
+// conflict is used only to leave the conflicted rows as is if update is empty,
+// because MySQL applies it to the conflict of any unique index.
+// The inserted values are referred by the VALUES() function to support
+// MySQL 5.x and MariaDB, although it is deprecated since MySQL 8.0.20 in
+// favor of the row alias, and MySQL 8.0 reports a warning for it.
+func (d *MySQLDialect) OnConflict(conflict, update []string) string {
+	if len(update) < 1 {
+		// no-op update.
+		return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s = %s", d.Quote(conflict[0]), d.Quote(conflict[0]))
+	}
+	sets := make([]string, len(update))
+	for i, column := range update {
+		sets[i] = fmt.Sprintf("%s = VALUES(%s)", d.Quote(column), d.Quote(column))
+	}
+	return fmt.Sprintf("ON DUPLICATE KEY UPDATE %s", strings.Join(sets, ", "))
+}
+
+// BulkInsertIDStrategy returns InsertIDRowByRow, because the auto-increment
+// values of a multi-row "INSERT" may not be consecutive in the "interleaved"
+// lock mode of InnoDB, that is default since MySQL 8.0, or if
+// auto_increment_increment is greater than 1.
+func (d *MySQLDialect) BulkInsertIDStrategy() InsertIDStrategy {
+	return InsertIDRowByRow
+}
+
+// Returning always returns empty string because MySQL doesn't support the
+// "RETURNING" clause.
+func (d *MySQLDialect) Returning(columns []string) string {
+	return ""
+}
+
+// MaxParameters returns 65535, that is the maximum number of placeholders of
+// a prepared statement. Note that the statement may also be limited by
+// max_allowed_packet of the server.
+func (d *MySQLDialect) MaxParameters() int {
+	return 65535
+}
+
+// CopyFrom always returns empty string because "LOAD DATA" of MySQL can't be
+// used through the prepared statement.
+func (d *MySQLDialect) CopyFrom(table string, columns []string) string {
+	return ""
+}
+
+// SupportsTransactionalDDL returns false because the DDL of MySQL causes an
+// implicit commit.
+func (d *MySQLDialect) SupportsTransactionalDDL() bool {
+	return false
+}
+
+// AdvisoryLock returns "GET_LOCK" SQL that waits for the lock infinitely.
+func (d *MySQLDialect) AdvisoryLock(name string) (string, []interface{}) {
+	return "SELECT GET_LOCK(?, -1)", []interface{}{name}
+}
+
+// AdvisoryUnlock returns "RELEASE_LOCK" SQL.
+func (d *MySQLDialect) AdvisoryUnlock(name string) (string, []interface{}) {
+	return "SELECT RELEASE_LOCK(?)", []interface{}{name}
+}
+
+// Tables returns the SQL that uses "information_schema.tables".
+func (d *MySQLDialect) Tables() string {
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name"
+}
+
+// PrimaryKey returns the SQL that uses "information_schema.key_column_usage".
+func (d *MySQLDialect) PrimaryKey(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT column_name FROM information_schema.key_column_usage WHERE table_schema = DATABASE() AND table_name = %s AND constraint_name = 'PRIMARY' ORDER BY ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// Columns returns the SQL that uses "information_schema.columns".
+func (d *MySQLDialect) Columns(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT column_name, column_type, is_nullable = 'YES', column_default FROM information_schema.columns WHERE table_schema = DATABASE() AND table_name = %s ORDER BY ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// Indexes returns the SQL that uses "information_schema.statistics".
+func (d *MySQLDialect) Indexes(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT index_name, non_unique = 0, column_name FROM information_schema.statistics WHERE table_schema = DATABASE() AND table_name = %s AND index_name <> 'PRIMARY' ORDER BY index_name, seq_in_index", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// UniqueConstraints returns the SQL that uses "information_schema.table_constraints"
+// and "information_schema.key_column_usage".
+func (d *MySQLDialect) UniqueConstraints(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT tc.constraint_name, kcu.column_name FROM information_schema.table_constraints AS tc"+
+		" JOIN information_schema.key_column_usage AS kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.table_name = tc.table_name AND kcu.constraint_name = tc.constraint_name"+
+		" WHERE tc.table_schema = DATABASE() AND tc.table_name = %s AND tc.constraint_type = 'UNIQUE'"+
+		" ORDER BY tc.constraint_name, kcu.ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// ForeignKeys returns the SQL that uses "information_schema.key_column_usage".
+func (d *MySQLDialect) ForeignKeys(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT constraint_name, column_name, referenced_table_name, referenced_column_name FROM information_schema.key_column_usage"+
+		" WHERE table_schema = DATABASE() AND table_name = %s AND referenced_table_name IS NOT NULL"+
+		" ORDER BY constraint_name, ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// DropColumn returns "ALTER TABLE ... DROP COLUMN" SQL.
+func (d *MySQLDialect) DropColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.Quote(table), d.Quote(column))
+}
+
+// AlterColumn returns "ALTER TABLE ... MODIFY COLUMN" SQL.
+func (d *MySQLDialect) AlterColumn(table, column, typ string, allowNull bool, def string) []string {
+	definition := []string{d.Quote(column), typ}
+	if !allowNull {
+		definition = append(definition, "NOT NULL")
+	}
+	if def != "" {
+		definition = append(definition, def)
+	}
+	return []string{fmt.Sprintf("ALTER TABLE %s MODIFY COLUMN %s", d.Quote(table), strings.Join(definition, " "))}
+}
+
+// StartTransaction always returns nil because the MySQL driver supports
+// the isolation level and read-only mode.
+func (d *MySQLDialect) StartTransaction(opts *sql.TxOptions) ([]string, error) {
+	return nil, nil
+}
+
 func (d *MySQLDialect) varchar(size uint64) string {
 	switch {
 	case size == 0:
@@ -307,6 +740,174 @@ func (d *PostgresDialect) LastInsertId() string {
 	return `SELECT lastval()`
 }
 
+// IsRetryableError returns whether the err is serialization_failure or deadlock_detected.
+func (d *PostgresDialect) IsRetryableError(err error) bool {
+	code, ok := driverErrorField(err, "Code")
+	if !ok {
+		return false
+	}
+	switch code {
+	case "40001", "40P01": // serialization_failure, deadlock_detected.
+		return true
+	}
+	return false
+}
+
+// SavePoint returns an SQL to create a savepoint named name.
+func (d *PostgresDialect) SavePoint(name string) string {
+	return fmt.Sprintf("SAVEPOINT %s", d.Quote(name))
+}
+
+// ReleaseSavePoint returns an SQL to release the savepoint named name.
+func (d *PostgresDialect) ReleaseSavePoint(name string) string {
+	return fmt.Sprintf("RELEASE SAVEPOINT %s", d.Quote(name))
+}
+
+// RollbackToSavePoint returns an SQL to rollback to the savepoint named name.
+func (d *PostgresDialect) RollbackToSavePoint(name string) string {
+	return fmt.Sprintf("ROLLBACK TO SAVEPOINT %s", d.Quote(name))
+}
+
+// SupportsRowValueIn returns whether the row value constructor can be used in IN predicate.
+func (d *PostgresDialect) SupportsRowValueIn() bool {
+	return true
+}
+
+// OnConflict returns "ON CONFLICT ... DO UPDATE" or "ON CONFLICT ... DO NOTHING" clause.
+func (d *PostgresDialect) OnConflict(conflict, update []string) string {
+	return onConflict(d, conflict, update)
+}
+
+// BulkInsertIDStrategy returns InsertIDReturning.
+func (d *PostgresDialect) BulkInsertIDStrategy() InsertIDStrategy {
+	return InsertIDReturning
+}
+
+// Returning returns "RETURNING" clause.
+func (d *PostgresDialect) Returning(columns []string) string {
+	return returning(d, columns)
+}
+
+// MaxParameters returns 65535, that is the maximum number of parameters of
+// the extended query protocol.
+func (d *PostgresDialect) MaxParameters() int {
+	return 65535
+}
+
+// CopyFrom returns "COPY ... FROM STDIN" SQL, that is supported by the
+// github.com/lib/pq driver.
+func (d *PostgresDialect) CopyFrom(table string, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = d.Quote(column)
+	}
+	return fmt.Sprintf("COPY %s (%s) FROM STDIN", d.Quote(table), strings.Join(quoted, ", "))
+}
+
+// SupportsTransactionalDDL returns true because PostgreSQL can rollback the DDL.
+func (d *PostgresDialect) SupportsTransactionalDDL() bool {
+	return true
+}
+
+// AdvisoryLock returns "pg_advisory_lock" SQL. The key of the lock is the
+// hash of name. pg_advisory_lock returns void, so it's called in FROM clause
+// to return true.
+func (d *PostgresDialect) AdvisoryLock(name string) (string, []interface{}) {
+	return "SELECT true FROM pg_advisory_lock($1)", []interface{}{lockKey(name)}
+}
+
+// AdvisoryUnlock returns "pg_advisory_unlock" SQL.
+func (d *PostgresDialect) AdvisoryUnlock(name string) (string, []interface{}) {
+	return "SELECT pg_advisory_unlock($1)", []interface{}{lockKey(name)}
+}
+
+// Tables returns the SQL that uses "information_schema.tables".
+func (d *PostgresDialect) Tables() string {
+	return "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name"
+}
+
+// PrimaryKey returns the SQL that uses "information_schema.table_constraints"
+// and "information_schema.key_column_usage".
+func (d *PostgresDialect) PrimaryKey(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT kcu.column_name FROM information_schema.table_constraints AS tc"+
+		" JOIN information_schema.key_column_usage AS kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name"+
+		" WHERE tc.table_schema = current_schema() AND tc.table_name = %s AND tc.constraint_type = 'PRIMARY KEY'"+
+		" ORDER BY kcu.ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// Columns returns the SQL that uses "information_schema.columns".
+// The length of character types and the precision and scale of numeric
+// types are added to the SQL type.
+func (d *PostgresDialect) Columns(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT column_name, CASE"+
+		" WHEN character_maximum_length IS NOT NULL THEN data_type || '(' || character_maximum_length || ')'"+
+		" WHEN data_type = 'numeric' AND numeric_precision IS NOT NULL THEN data_type || '(' || numeric_precision || ', ' || numeric_scale || ')'"+
+		" ELSE data_type END,"+
+		" is_nullable = 'YES', column_default FROM information_schema.columns WHERE table_schema = current_schema() AND table_name = %s ORDER BY ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// Indexes returns the SQL that uses "pg_index", because information_schema
+// doesn't have the indexes.
+func (d *PostgresDialect) Indexes(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT i.relname, ix.indisunique, a.attname FROM pg_index ix"+
+		" JOIN pg_class i ON i.oid = ix.indexrelid"+
+		" JOIN pg_class t ON t.oid = ix.indrelid"+
+		" JOIN pg_attribute a ON a.attrelid = t.oid AND a.attnum = ANY(ix.indkey)"+
+		" WHERE t.relname = %s AND pg_table_is_visible(t.oid) AND NOT ix.indisprimary"+
+		" ORDER BY i.relname, array_position(ix.indkey::int2[], a.attnum)", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// UniqueConstraints returns the SQL that uses "information_schema.table_constraints"
+// and "information_schema.key_column_usage".
+func (d *PostgresDialect) UniqueConstraints(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT tc.constraint_name, kcu.column_name FROM information_schema.table_constraints AS tc"+
+		" JOIN information_schema.key_column_usage AS kcu ON kcu.constraint_schema = tc.constraint_schema AND kcu.constraint_name = tc.constraint_name"+
+		" WHERE tc.table_schema = current_schema() AND tc.table_name = %s AND tc.constraint_type = 'UNIQUE'"+
+		" ORDER BY tc.constraint_name, kcu.ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// ForeignKeys returns the SQL that uses "information_schema.key_column_usage"
+// and "information_schema.referential_constraints".
+func (d *PostgresDialect) ForeignKeys(table string) (string, []interface{}) {
+	return fmt.Sprintf("SELECT kcu.constraint_name, kcu.column_name, ref.table_name, ref.column_name FROM information_schema.key_column_usage AS kcu"+
+		" JOIN information_schema.referential_constraints AS rc ON rc.constraint_schema = kcu.constraint_schema AND rc.constraint_name = kcu.constraint_name"+
+		" JOIN information_schema.key_column_usage AS ref ON ref.constraint_schema = rc.unique_constraint_schema AND ref.constraint_name = rc.unique_constraint_name AND ref.ordinal_position = kcu.position_in_unique_constraint"+
+		" WHERE kcu.table_schema = current_schema() AND kcu.table_name = %s"+
+		" ORDER BY kcu.constraint_name, kcu.ordinal_position", d.PlaceHolder(0)), []interface{}{table}
+}
+
+// DropColumn returns "ALTER TABLE ... DROP COLUMN" SQL.
+func (d *PostgresDialect) DropColumn(table, column string) string {
+	return fmt.Sprintf("ALTER TABLE %s DROP COLUMN %s", d.Quote(table), d.Quote(column))
+}
+
+// AlterColumn returns "ALTER TABLE ... ALTER COLUMN" SQLs to change the type,
+// the nullability and the default value. The current default value is
+// dropped first, because it may not be cast to the new type, and the values
+// are cast to the new type by "USING".
+func (d *PostgresDialect) AlterColumn(table, column, typ string, allowNull bool, def string) []string {
+	prefix := fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s", d.Quote(table), d.Quote(column))
+	queries := []string{
+		fmt.Sprintf("%s DROP DEFAULT", prefix),
+		fmt.Sprintf("%s TYPE %s USING %s::%s", prefix, typ, d.Quote(column), typ),
+	}
+	if allowNull {
+		queries = append(queries, fmt.Sprintf("%s DROP NOT NULL", prefix))
+	} else {
+		queries = append(queries, fmt.Sprintf("%s SET NOT NULL", prefix))
+	}
+	if def != "" {
+		queries = append(queries, fmt.Sprintf("%s SET %s", prefix, def))
+	}
+	return queries
+}
+
+// StartTransaction always returns nil because the PostgreSQL driver supports
+// the isolation level and read-only mode.
+func (d *PostgresDialect) StartTransaction(opts *sql.TxOptions) ([]string, error) {
+	return nil, nil
+}
+
 func (d *PostgresDialect) smallint(autoIncrement bool) string {
 	if autoIncrement {
 		return "smallserial"
@@ -338,3 +939,36 @@ func (d *PostgresDialect) varchar(size uint64) string {
 	}
 	return "text"
 }
+
+// onConflict returns "ON CONFLICT" clause of the standard-like syntax that is
+// used by SQLite3 and PostgreSQL.
+func onConflict(d Dialect, conflict, update []string) string {
+	targets := make([]string, len(conflict))
+	for i, column := range conflict {
+		targets[i] = d.Quote(column)
+	}
+	if len(update) < 1 {
+		return fmt.Sprintf("ON CONFLICT (%s) DO NOTHING", strings.Join(targets, ", "))
+	}
+	sets := make([]string, len(update))
+	for i, column := range update {
+		sets[i] = fmt.Sprintf("%s = EXCLUDED.%s", d.Quote(column), d.Quote(column))
+	}
+	return fmt.Sprintf("ON CONFLICT (%s) DO UPDATE SET %s", strings.Join(targets, ", "), strings.Join(sets, ", "))
+}
+
+// returning returns "RETURNING" clause of columns that is quoted by d.
+func returning(d Dialect, columns []string) string {
+	quoted := make([]string, len(columns))
+	for i, column := range columns {
+		quoted[i] = d.Quote(column)
+	}
+	return fmt.Sprintf("RETURNING %s", strings.Join(quoted, ", "))
+}
+
+// lockKey returns the integer key of the lock named name.
+func lockKey(name string) int64 {
+	h := fnv.New64a()
+	h.Write([]byte(name))
+	return int64(h.Sum64())
+}
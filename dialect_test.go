@@ -2,7 +2,9 @@ package genmai
 
 import (
 	"database/sql"
+	"fmt"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 )
@@ -435,6 +437,181 @@ func TestSQLite3Dialect_LastInsertID(t *testing.T) {
 	}
 }
 
+func TestSQLite3Dialect_IsRetryableError(t *testing.T) {
+	d := &SQLite3Dialect{}
+	for _, v := range []struct {
+		err    error
+		expect bool
+	}{
+		{nil, false},
+		{fmt.Errorf("database is locked"), false},
+		{&testDriverError{Code: 5}, true},
+		{&testDriverError{Code: 6}, true},
+		{&testDriverError{Code: 19}, false},
+	} {
+		actual := d.IsRetryableError(v.err)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SQLite3Dialect.IsRetryableError(%#v) => %#v; want %#v`, v.err, actual, expect)
+		}
+	}
+}
+
+func TestSQLite3Dialect_SavePoint(t *testing.T) {
+	d := &SQLite3Dialect{}
+	for _, v := range []struct {
+		actual, expect string
+	}{
+		{d.SavePoint("sp1"), `SAVEPOINT "sp1"`},
+		{d.ReleaseSavePoint("sp1"), `RELEASE SAVEPOINT "sp1"`},
+		{d.RollbackToSavePoint("sp1"), `ROLLBACK TO SAVEPOINT "sp1"`},
+	} {
+		if !reflect.DeepEqual(v.actual, v.expect) {
+			t.Errorf(`SQLite3Dialect => %#v; want %#v`, v.actual, v.expect)
+		}
+	}
+}
+
+func TestSQLite3Dialect_StartTransaction(t *testing.T) {
+	d := &SQLite3Dialect{}
+	for _, v := range []struct {
+		opts   *sql.TxOptions
+		expect []string
+	}{
+		{nil, nil},
+		{&sql.TxOptions{}, nil},
+		{&sql.TxOptions{Isolation: sql.LevelReadCommitted}, nil},
+		{&sql.TxOptions{Isolation: sql.LevelSerializable}, []string{"BEGIN IMMEDIATE"}},
+		{&sql.TxOptions{Isolation: sql.LevelLinearizable}, []string{"BEGIN EXCLUSIVE"}},
+	} {
+		actual, err := d.StartTransaction(v.opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SQLite3Dialect.StartTransaction(%#v) => %#v; want %#v`, v.opts, actual, expect)
+		}
+	}
+
+	opts := &sql.TxOptions{ReadOnly: true}
+	if _, err := d.StartTransaction(opts); err == nil {
+		t.Errorf(`SQLite3Dialect.StartTransaction(%#v) => _, nil; want error`, opts)
+	}
+}
+
+func TestSQLite3Dialect_OnConflict(t *testing.T) {
+	d := &SQLite3Dialect{}
+	for _, v := range []struct {
+		conflict, update []string
+		expect           string
+	}{
+		{[]string{"email"}, []string{"name", "age"}, `ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name", "age" = EXCLUDED."age"`},
+		{[]string{"a", "b"}, nil, `ON CONFLICT ("a", "b") DO NOTHING`},
+	} {
+		actual := d.OnConflict(v.conflict, v.update)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SQLite3Dialect.OnConflict(%#v, %#v) => %#v; want %#v`, v.conflict, v.update, actual, expect)
+		}
+	}
+}
+
+func TestSQLite3Dialect_Returning(t *testing.T) {
+	d := &SQLite3Dialect{}
+	actual := d.Returning([]string{"id", "name"})
+	expect := `RETURNING "id", "name"`
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SQLite3Dialect.Returning(%#v) => %#v; want %#v`, []string{"id", "name"}, actual, expect)
+	}
+}
+
+func TestSQLite3Dialect_CopyFrom(t *testing.T) {
+	d := &SQLite3Dialect{}
+	actual := d.CopyFrom("test_table", []string{"name", "addr"})
+	expect := ""
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SQLite3Dialect.CopyFrom(%#v, %#v) => %#v; want %#v`, "test_table", []string{"name", "addr"}, actual, expect)
+	}
+}
+
+func TestSQLite3Dialect_SupportsTransactionalDDL(t *testing.T) {
+	d := &SQLite3Dialect{}
+	actual := d.SupportsTransactionalDDL()
+	expect := true
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SQLite3Dialect.SupportsTransactionalDDL() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestSQLite3Dialect_AdvisoryLock(t *testing.T) {
+	d := &SQLite3Dialect{}
+	for _, v := range []struct {
+		name  string
+		fn    func(name string) (string, []interface{})
+		query string
+		args  []interface{}
+	}{
+		{"AdvisoryLock", d.AdvisoryLock, "", []interface{}(nil)},
+		{"AdvisoryUnlock", d.AdvisoryUnlock, "", []interface{}(nil)},
+	} {
+		query, args := v.fn("genmai_migrations")
+		if actual, expect := query, v.query; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SQLite3Dialect.%s("genmai_migrations") => %#v; want %#v`, v.name, actual, expect)
+		}
+		if actual, expect := args, v.args; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SQLite3Dialect.%s("genmai_migrations") => args %#v; want %#v`, v.name, actual, expect)
+		}
+	}
+}
+
+func TestSQLite3Dialect_schemaQueries(t *testing.T) {
+	d := &SQLite3Dialect{}
+	table := `it's\`
+	for _, v := range []struct {
+		name string
+		fn   func(table string) (string, []interface{})
+	}{
+		{"Columns", d.Columns},
+		{"PrimaryKey", d.PrimaryKey},
+		{"Indexes", d.Indexes},
+		{"UniqueConstraints", d.UniqueConstraints},
+		{"ForeignKeys", d.ForeignKeys},
+	} {
+		query, args := v.fn(table)
+		if strings.Contains(query, "it's") || !strings.Contains(query, "(?)") {
+			t.Errorf(`SQLite3Dialect.%s(%#v) => %#v; want the table name as a placeholder`, v.name, table, query)
+		}
+		if actual, expect := args, []interface{}{table}; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`SQLite3Dialect.%s(%#v) => args %#v; want %#v`, v.name, table, actual, expect)
+		}
+	}
+}
+func TestSQLite3Dialect_AlterColumn(t *testing.T) {
+	d := &SQLite3Dialect{}
+	actual := d.AlterColumn("test_table", "name", "text", false, "")
+	if actual != nil {
+		t.Errorf(`SQLite3Dialect.AlterColumn(...) => %#v; want nil`, actual)
+	}
+}
+
+func TestSQLite3Dialect_DropColumn(t *testing.T) {
+	d := &SQLite3Dialect{}
+	actual := d.DropColumn("test_table", "name")
+	if expect := ""; actual != expect {
+		t.Errorf(`SQLite3Dialect.DropColumn(...) => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestSQLite3Dialect_Tables(t *testing.T) {
+	d := &SQLite3Dialect{}
+	actual := d.Tables()
+	expect := `SELECT "name" FROM "sqlite_master" WHERE "type" = 'table' AND "name" NOT LIKE 'sqlite\_%' ESCAPE '\' ORDER BY "name"`
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`SQLite3Dialect.Tables() => %#v; want %#v`, actual, expect)
+	}
+}
+
 func Test_MySQLDialect_Name(t *testing.T) {
 	d := &MySQLDialect{}
 	actual := d.Name()
@@ -1162,6 +1339,190 @@ func TestMySQLDialect_LastInsertID(t *testing.T) {
 	}
 }
 
+func TestMySQLDialect_IsRetryableError(t *testing.T) {
+	d := &MySQLDialect{}
+	for _, v := range []struct {
+		err    error
+		expect bool
+	}{
+		{nil, false},
+		{fmt.Errorf("Error 1213: Deadlock found"), false},
+		{&testDriverError{Number: 1213}, true},
+		{&testDriverError{Number: 1205}, true},
+		{&testDriverError{Number: 1062}, false},
+	} {
+		actual := d.IsRetryableError(v.err)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`MySQLDialect.IsRetryableError(%#v) => %#v; want %#v`, v.err, actual, expect)
+		}
+	}
+}
+
+func TestMySQLDialect_SavePoint(t *testing.T) {
+	d := &MySQLDialect{}
+	for _, v := range []struct {
+		actual, expect string
+	}{
+		{d.SavePoint("sp1"), "SAVEPOINT `sp1`"},
+		{d.ReleaseSavePoint("sp1"), "RELEASE SAVEPOINT `sp1`"},
+		{d.RollbackToSavePoint("sp1"), "ROLLBACK TO SAVEPOINT `sp1`"},
+	} {
+		if !reflect.DeepEqual(v.actual, v.expect) {
+			t.Errorf(`MySQLDialect => %#v; want %#v`, v.actual, v.expect)
+		}
+	}
+}
+
+func TestMySQLDialect_StartTransaction(t *testing.T) {
+	d := &MySQLDialect{}
+	for _, opts := range []*sql.TxOptions{
+		nil,
+		{},
+		{ReadOnly: true},
+		{Isolation: sql.LevelSerializable, ReadOnly: true},
+	} {
+		actual, err := d.StartTransaction(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expect []string
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`MySQLDialect.StartTransaction(%#v) => %#v; want %#v`, opts, actual, expect)
+		}
+	}
+}
+
+func TestMySQLDialect_OnConflict(t *testing.T) {
+	d := &MySQLDialect{}
+	for _, v := range []struct {
+		conflict, update []string
+		expect           string
+	}{
+		{[]string{"email"}, []string{"name", "age"}, "ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `age` = VALUES(`age`)"},
+		{[]string{"a", "b"}, nil, "ON DUPLICATE KEY UPDATE `a` = `a`"},
+	} {
+		actual := d.OnConflict(v.conflict, v.update)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`MySQLDialect.OnConflict(%#v, %#v) => %#v; want %#v`, v.conflict, v.update, actual, expect)
+		}
+	}
+}
+
+func TestMySQLDialect_Returning(t *testing.T) {
+	d := &MySQLDialect{}
+	actual := d.Returning([]string{"id", "name"})
+	expect := ""
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`MySQLDialect.Returning(%#v) => %#v; want %#v`, []string{"id", "name"}, actual, expect)
+	}
+}
+
+func TestMySQLDialect_BulkInsertIDStrategy(t *testing.T) {
+	d := &MySQLDialect{}
+	actual := d.BulkInsertIDStrategy()
+	expect := InsertIDRowByRow
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`MySQLDialect.BulkInsertIDStrategy() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestMySQLDialect_CopyFrom(t *testing.T) {
+	d := &MySQLDialect{}
+	actual := d.CopyFrom("test_table", []string{"name", "addr"})
+	expect := ""
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`MySQLDialect.CopyFrom(%#v, %#v) => %#v; want %#v`, "test_table", []string{"name", "addr"}, actual, expect)
+	}
+}
+
+func TestMySQLDialect_SupportsTransactionalDDL(t *testing.T) {
+	d := &MySQLDialect{}
+	actual := d.SupportsTransactionalDDL()
+	expect := false
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`MySQLDialect.SupportsTransactionalDDL() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestMySQLDialect_AdvisoryLock(t *testing.T) {
+	d := &MySQLDialect{}
+	for _, v := range []struct {
+		name  string
+		fn    func(name string) (string, []interface{})
+		query string
+		args  []interface{}
+	}{
+		{"AdvisoryLock", d.AdvisoryLock, `SELECT GET_LOCK(?, -1)`, []interface{}{"genmai_migrations"}},
+		{"AdvisoryUnlock", d.AdvisoryUnlock, `SELECT RELEASE_LOCK(?)`, []interface{}{"genmai_migrations"}},
+	} {
+		query, args := v.fn("genmai_migrations")
+		if actual, expect := query, v.query; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`MySQLDialect.%s("genmai_migrations") => %#v; want %#v`, v.name, actual, expect)
+		}
+		if actual, expect := args, v.args; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`MySQLDialect.%s("genmai_migrations") => args %#v; want %#v`, v.name, actual, expect)
+		}
+	}
+}
+
+func TestMySQLDialect_schemaQueries(t *testing.T) {
+	d := &MySQLDialect{}
+	table := `it's\`
+	for _, v := range []struct {
+		name string
+		fn   func(table string) (string, []interface{})
+	}{
+		{"Columns", d.Columns},
+		{"PrimaryKey", d.PrimaryKey},
+		{"Indexes", d.Indexes},
+		{"UniqueConstraints", d.UniqueConstraints},
+		{"ForeignKeys", d.ForeignKeys},
+	} {
+		query, args := v.fn(table)
+		if strings.Contains(query, "it's") || !strings.Contains(query, "= ?") {
+			t.Errorf(`MySQLDialect.%s(%#v) => %#v; want the table name as a placeholder`, v.name, table, query)
+		}
+		if actual, expect := args, []interface{}{table}; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`MySQLDialect.%s(%#v) => args %#v; want %#v`, v.name, table, actual, expect)
+		}
+	}
+}
+func TestMySQLDialect_AlterColumn(t *testing.T) {
+	d := &MySQLDialect{}
+	for _, v := range []struct {
+		allowNull bool
+		def       string
+		expect    []string
+	}{
+		{false, "", []string{"ALTER TABLE `test_table` MODIFY COLUMN `name` VARCHAR(255) NOT NULL"}},
+		{true, "DEFAULT 'none'", []string{"ALTER TABLE `test_table` MODIFY COLUMN `name` VARCHAR(255) DEFAULT 'none'"}},
+	} {
+		actual := d.AlterColumn("test_table", "name", "VARCHAR(255)", v.allowNull, v.def)
+		if !reflect.DeepEqual(actual, v.expect) {
+			t.Errorf(`MySQLDialect.AlterColumn(..., %v, %#v) => %#v; want %#v`, v.allowNull, v.def, actual, v.expect)
+		}
+	}
+}
+
+func TestMySQLDialect_DropColumn(t *testing.T) {
+	d := &MySQLDialect{}
+	actual := d.DropColumn("test_table", "name")
+	if expect := "ALTER TABLE `test_table` DROP COLUMN `name`"; actual != expect {
+		t.Errorf(`MySQLDialect.DropColumn(...) => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestMySQLDialect_Tables(t *testing.T) {
+	d := &MySQLDialect{}
+	actual := d.Tables()
+	expect := "SELECT table_name FROM information_schema.tables WHERE table_schema = DATABASE() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`MySQLDialect.Tables() => %#v; want %#v`, actual, expect)
+	}
+}
+
 func Test_PostgresDialect_Name(t *testing.T) {
 	d := &PostgresDialect{}
 	actual := d.Name()
@@ -1826,3 +2187,206 @@ func TestPostgresDialect_LastInsertID(t *testing.T) {
 		t.Errorf(`PostgresDialect.LastInsertId() => %#v; want %#v`, actual, expect)
 	}
 }
+
+func TestPostgresDialect_IsRetryableError(t *testing.T) {
+	d := &PostgresDialect{}
+	for _, v := range []struct {
+		err    error
+		expect bool
+	}{
+		{nil, false},
+		{fmt.Errorf("pq: could not serialize access"), false},
+		{&testPostgresError{Code: "40001"}, true},
+		{&testPostgresError{Code: "40P01"}, true},
+		{&testPostgresError{Code: "23505"}, false},
+	} {
+		actual := d.IsRetryableError(v.err)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`PostgresDialect.IsRetryableError(%#v) => %#v; want %#v`, v.err, actual, expect)
+		}
+	}
+}
+
+func TestPostgresDialect_SavePoint(t *testing.T) {
+	d := &PostgresDialect{}
+	for _, v := range []struct {
+		actual, expect string
+	}{
+		{d.SavePoint("sp1"), `SAVEPOINT "sp1"`},
+		{d.ReleaseSavePoint("sp1"), `RELEASE SAVEPOINT "sp1"`},
+		{d.RollbackToSavePoint("sp1"), `ROLLBACK TO SAVEPOINT "sp1"`},
+	} {
+		if !reflect.DeepEqual(v.actual, v.expect) {
+			t.Errorf(`PostgresDialect => %#v; want %#v`, v.actual, v.expect)
+		}
+	}
+}
+
+func TestPostgresDialect_StartTransaction(t *testing.T) {
+	d := &PostgresDialect{}
+	for _, opts := range []*sql.TxOptions{
+		nil,
+		{},
+		{ReadOnly: true},
+		{Isolation: sql.LevelSerializable, ReadOnly: true},
+	} {
+		actual, err := d.StartTransaction(opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var expect []string
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`PostgresDialect.StartTransaction(%#v) => %#v; want %#v`, opts, actual, expect)
+		}
+	}
+}
+
+func TestPostgresDialect_OnConflict(t *testing.T) {
+	d := &PostgresDialect{}
+	for _, v := range []struct {
+		conflict, update []string
+		expect           string
+	}{
+		{[]string{"email"}, []string{"name", "age"}, `ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name", "age" = EXCLUDED."age"`},
+		{[]string{"a", "b"}, nil, `ON CONFLICT ("a", "b") DO NOTHING`},
+	} {
+		actual := d.OnConflict(v.conflict, v.update)
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`PostgresDialect.OnConflict(%#v, %#v) => %#v; want %#v`, v.conflict, v.update, actual, expect)
+		}
+	}
+}
+
+func TestPostgresDialect_Returning(t *testing.T) {
+	d := &PostgresDialect{}
+	actual := d.Returning([]string{"id", "name"})
+	expect := `RETURNING "id", "name"`
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`PostgresDialect.Returning(%#v) => %#v; want %#v`, []string{"id", "name"}, actual, expect)
+	}
+}
+
+func TestPostgresDialect_CopyFrom(t *testing.T) {
+	d := &PostgresDialect{}
+	actual := d.CopyFrom("test_table", []string{"name", "addr"})
+	expect := `COPY "test_table" ("name", "addr") FROM STDIN`
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`PostgresDialect.CopyFrom(%#v, %#v) => %#v; want %#v`, "test_table", []string{"name", "addr"}, actual, expect)
+	}
+}
+
+func TestPostgresDialect_SupportsTransactionalDDL(t *testing.T) {
+	d := &PostgresDialect{}
+	actual := d.SupportsTransactionalDDL()
+	expect := true
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`PostgresDialect.SupportsTransactionalDDL() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestPostgresDialect_AdvisoryLock(t *testing.T) {
+	d := &PostgresDialect{}
+	for _, v := range []struct {
+		name  string
+		fn    func(name string) (string, []interface{})
+		query string
+		args  []interface{}
+	}{
+		{"AdvisoryLock", d.AdvisoryLock, `SELECT true FROM pg_advisory_lock($1)`, []interface{}{int64(9158742868927705258)}},
+		{"AdvisoryUnlock", d.AdvisoryUnlock, `SELECT pg_advisory_unlock($1)`, []interface{}{int64(9158742868927705258)}},
+	} {
+		query, args := v.fn("genmai_migrations")
+		if actual, expect := query, v.query; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`PostgresDialect.%s("genmai_migrations") => %#v; want %#v`, v.name, actual, expect)
+		}
+		if actual, expect := args, v.args; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`PostgresDialect.%s("genmai_migrations") => args %#v; want %#v`, v.name, actual, expect)
+		}
+	}
+}
+
+func TestPostgresDialect_schemaQueries(t *testing.T) {
+	d := &PostgresDialect{}
+	table := `it's\`
+	for _, v := range []struct {
+		name string
+		fn   func(table string) (string, []interface{})
+	}{
+		{"Columns", d.Columns},
+		{"PrimaryKey", d.PrimaryKey},
+		{"Indexes", d.Indexes},
+		{"UniqueConstraints", d.UniqueConstraints},
+		{"ForeignKeys", d.ForeignKeys},
+	} {
+		query, args := v.fn(table)
+		if strings.Contains(query, "it's") || !strings.Contains(query, "= $1") {
+			t.Errorf(`PostgresDialect.%s(%#v) => %#v; want the table name as a placeholder`, v.name, table, query)
+		}
+		if actual, expect := args, []interface{}{table}; !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`PostgresDialect.%s(%#v) => args %#v; want %#v`, v.name, table, actual, expect)
+		}
+	}
+}
+func TestPostgresDialect_AlterColumn(t *testing.T) {
+	d := &PostgresDialect{}
+	for _, v := range []struct {
+		allowNull bool
+		def       string
+		expect    []string
+	}{
+		{false, "", []string{
+			`ALTER TABLE "test_table" ALTER COLUMN "name" DROP DEFAULT`,
+			`ALTER TABLE "test_table" ALTER COLUMN "name" TYPE varchar(255) USING "name"::varchar(255)`,
+			`ALTER TABLE "test_table" ALTER COLUMN "name" SET NOT NULL`,
+		}},
+		{true, "DEFAULT 'none'", []string{
+			`ALTER TABLE "test_table" ALTER COLUMN "name" DROP DEFAULT`,
+			`ALTER TABLE "test_table" ALTER COLUMN "name" TYPE varchar(255) USING "name"::varchar(255)`,
+			`ALTER TABLE "test_table" ALTER COLUMN "name" DROP NOT NULL`,
+			`ALTER TABLE "test_table" ALTER COLUMN "name" SET DEFAULT 'none'`,
+		}},
+	} {
+		actual := d.AlterColumn("test_table", "name", "varchar(255)", v.allowNull, v.def)
+		if !reflect.DeepEqual(actual, v.expect) {
+			t.Errorf(`PostgresDialect.AlterColumn(..., %v, %#v) => %#v; want %#v`, v.allowNull, v.def, actual, v.expect)
+		}
+	}
+}
+
+func TestPostgresDialect_DropColumn(t *testing.T) {
+	d := &PostgresDialect{}
+	actual := d.DropColumn("test_table", "name")
+	if expect := `ALTER TABLE "test_table" DROP COLUMN "name"`; actual != expect {
+		t.Errorf(`PostgresDialect.DropColumn(...) => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestPostgresDialect_Tables(t *testing.T) {
+	d := &PostgresDialect{}
+	actual := d.Tables()
+	expect := "SELECT table_name FROM information_schema.tables WHERE table_schema = current_schema() AND table_type = 'BASE TABLE' ORDER BY table_name"
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`PostgresDialect.Tables() => %#v; want %#v`, actual, expect)
+	}
+}
+
+// testDriverError imitates the error types of go-sqlite3 and mysql driver.
+type testDriverError struct {
+	Code   int
+	Number uint16
+}
+
+func (e *testDriverError) Error() string {
+	return fmt.Sprintf("code=%d number=%d", e.Code, e.Number)
+}
+
+// testPostgresError imitates the error type of pq driver.
+type testPostgresError struct {
+	Code string
+}
+
+func (e *testPostgresError) Error() string {
+	return e.Code
+}
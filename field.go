@@ -26,3 +26,34 @@ func (ts *TimeStamp) BeforeUpdate() error {
 	ts.UpdatedAt = now()
 	return nil
 }
+
+// DirtyTracker is an embeddable struct to enable the dirty tracking.
+// If a struct embeds DirtyTracker, the values of the fields will be
+// snapshotted when it is fetched by Select or Iterate, or written by Insert
+// or Update. After that, Update writes only the columns that have been
+// changed since the snapshot.
+//
+//     type User struct {
+//         genmai.DirtyTracker
+//         Id   int64 `db:"pk"`
+//         Name string
+//     }
+type DirtyTracker struct {
+	snapshot map[string]interface{} // values by column name.
+}
+
+// ResetSnapshot discards the snapshot. Update writes all columns until the
+// next snapshot is taken.
+func (dt *DirtyTracker) ResetSnapshot() {
+	dt.snapshot = nil
+}
+
+func (dt *DirtyTracker) dirtyTracker() *DirtyTracker {
+	return dt
+}
+
+// dirtyTracked is the interface that is implemented by the struct embedding
+// DirtyTracker.
+type dirtyTracked interface {
+	dirtyTracker() *DirtyTracker
+}
@@ -0,0 +1,321 @@
+package genmai
+
+import (
+	"bytes"
+	"context"
+	"database/sql"
+	"fmt"
+	"go/format"
+	"io"
+	"reflect"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+
+	"github.com/naoina/go-stringutil"
+)
+
+// GenerateOptions represents the options of Generate.
+type GenerateOptions struct {
+	// Package is the package name of the generated code.
+	// If it is empty, "models" will be used.
+	Package string
+
+	// Tables is the names of the tables to generate the structs.
+	// If it is empty, all tables in the database will be used.
+	Tables []string
+
+	// If NullTypes is true, the nullable columns are mapped to sql.NullBool,
+	// sql.NullInt64 and sql.NullString if possible, instead of pointers.
+	NullTypes bool
+}
+
+// Generate reads the schema of the tables from the database, and writes the
+// Go source code of the structs that are mapped to them to w.
+// The type of each field is the Go type that Dialect.SQLType maps to the SQL
+// type of the column, and the fields have "pk", "unique", "column", "size" and
+// "default" struct tags. opts can be nil.
+//
+//     var buf bytes.Buffer
+//     if err := db.Generate(&buf, &genmai.GenerateOptions{Package: "models"}); err != nil {
+//         return err
+//     }
+func (db *DB) Generate(w io.Writer, opts *GenerateOptions) error {
+	return db.GenerateContext(context.Background(), w, opts)
+}
+
+// GenerateContext is like Generate, but with context.
+func (db *DB) GenerateContext(ctx context.Context, w io.Writer, opts *GenerateOptions) error {
+	if opts == nil {
+		opts = &GenerateOptions{}
+	}
+	pkg := opts.Package
+	if pkg == "" {
+		pkg = "models"
+	}
+	tableNames := opts.Tables
+	if len(tableNames) < 1 {
+		var err error
+		if tableNames, err = db.TablesContext(ctx); err != nil {
+			return err
+		}
+	}
+	g := &generator{db: db, nullTypes: opts.NullTypes, imports: map[string]bool{}}
+	for _, name := range tableNames {
+		table, err := db.DescribeTableContext(ctx, name)
+		if err != nil {
+			return err
+		}
+		if table == nil {
+			return fmt.Errorf(`Generate: table "%s" doesn't exist`, name)
+		}
+		g.table(table)
+	}
+	var buf bytes.Buffer
+	fmt.Fprintf(&buf, "package %s\n\n", pkg)
+	if len(g.imports) > 0 {
+		var std, others []string
+		for path := range g.imports {
+			if strings.Contains(strings.SplitN(path, "/", 2)[0], ".") {
+				others = append(others, strconv.Quote(path))
+			} else {
+				std = append(std, strconv.Quote(path))
+			}
+		}
+		sort.Strings(std)
+		sort.Strings(others)
+		imports := strings.Join(std, "\n")
+		if len(std) > 0 && len(others) > 0 {
+			imports += "\n\n"
+		}
+		imports += strings.Join(others, "\n")
+		fmt.Fprintf(&buf, "import (\n%s\n)\n\n", imports)
+	}
+	buf.Write(g.buf.Bytes())
+	src, err := format.Source(buf.Bytes())
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(src)
+	return err
+}
+
+// generator generates the structs from the schema of the tables.
+type generator struct {
+	db        *DB
+	nullTypes bool
+	buf       bytes.Buffer
+	imports   map[string]bool // import paths that the generated code uses.
+}
+
+// table writes the struct of table.
+func (g *generator) table(table *Table) {
+	name := goIdentifier(table.Name)
+	pks := make(map[string]bool, len(table.PrimaryKey))
+	for _, column := range table.PrimaryKey {
+		pks[column] = true
+	}
+	uniques := map[string]bool{}
+	for _, index := range table.Indexes {
+		if index.Unique && len(index.Columns) == 1 {
+			uniques[index.Columns[0]] = true
+		}
+	}
+	for _, c := range table.UniqueConstraints {
+		if len(c.Columns) == 1 {
+			uniques[c.Columns[0]] = true
+		}
+	}
+	fmt.Fprintf(&g.buf, "// %s represents the table %q.\n", name, table.Name)
+	fmt.Fprintf(&g.buf, "type %s struct {\n", name)
+	for _, column := range table.Columns {
+		field := goIdentifier(column.Name)
+		if pks[column.Name] {
+			// primary key can't be NULL even if the database reports.
+			column.Nullable = false
+		}
+		typ, size := g.goType(column)
+		var tags []string
+		var options []string
+		if pks[column.Name] {
+			options = append(options, "pk")
+		} else if uniques[column.Name] {
+			options = append(options, "unique")
+		}
+		if len(options) > 0 {
+			tags = append(tags, fmt.Sprintf(`%s:"%s"`, dbTag, strings.Join(options, ",")))
+		}
+		if stringutil.ToSnakeCase(field) != column.Name {
+			tags = append(tags, fmt.Sprintf(`%s:"%s"`, dbColumnTag, column.Name))
+		}
+		if size > 0 {
+			tags = append(tags, fmt.Sprintf(`%s:"%d"`, dbSizeTag, size))
+		}
+		if column.Default != nil && !isSequenceDefault(*column.Default) {
+			tags = append(tags, fmt.Sprintf(`%s:%s`, dbDefaultTag, strconv.Quote(g.defaultValue(*column.Default))))
+		}
+		if len(tags) > 0 {
+			fmt.Fprintf(&g.buf, "\t%s %s `%s`\n", field, typ, strings.Join(tags, " "))
+		} else {
+			fmt.Fprintf(&g.buf, "\t%s %s\n", field, typ)
+		}
+	}
+	fmt.Fprintf(&g.buf, "}\n\n")
+	if stringutil.ToSnakeCase(name) != table.Name {
+		fmt.Fprintf(&g.buf, "// TableName returns the name of the table.\n")
+		fmt.Fprintf(&g.buf, "func (*%s) TableName() string {\n\treturn %q\n}\n\n", name, table.Name)
+	}
+}
+
+// goTypeCandidates is the Go types that are mapped to the SQL types by
+// Dialect.SQLType, in order of priority.
+var goTypeCandidates = []interface{}{
+	false,
+	int64(0),
+	int(0),
+	int16(0),
+	"",
+	time.Time{},
+	Rat{},
+	Float64(0),
+	[]byte(nil),
+}
+
+// sqlTypeSizeRegexp is the regexp to extract the size from the SQL type.
+// e.g. "varchar(255)"
+var sqlTypeSizeRegexp = regexp.MustCompile(`\((\d+)\)`)
+
+// goType returns the Go type of column and the value of "size" tag.
+// It finds the type that Dialect.SQLType maps to the SQL type of column.
+// If no type is found, it guesses the type from the name of the SQL type.
+func (g *generator) goType(column Column) (typ string, size uint64) {
+	var sizes []uint64
+	if m := sqlTypeSizeRegexp.FindStringSubmatch(column.Type); m != nil {
+		if n, err := strconv.ParseUint(m[1], 10, 64); err == nil {
+			sizes = append(sizes, n)
+		}
+	}
+	sizes = append(sizes, 0)
+	var found interface{}
+Candidates:
+	for _, v := range goTypeCandidates {
+		for _, s := range sizes {
+			if sqlType, ok := g.sqlType(v, s); ok && sameSQLType(sqlType, column.Type) {
+				found = v
+				// "size" tag is needed only if it changes the SQL type.
+				if s > 0 {
+					if sqlType, _ := g.sqlType(v, 0); !sameSQLType(sqlType, column.Type) {
+						size = s
+					}
+				}
+				break Candidates
+			}
+		}
+	}
+	if found == nil {
+		found = guessGoType(column.Type)
+	}
+	return g.typeName(found, column.Nullable), size
+}
+
+// sqlType returns the SQL type of v, or false if the dialect doesn't support v.
+func (g *generator) sqlType(v interface{}, size uint64) (typ string, ok bool) {
+	defer func() {
+		if recover() != nil {
+			typ, ok = "", false
+		}
+	}()
+	typ, _ = g.db.dialect.SQLType(v, false, size)
+	return typ, true
+}
+
+// typeName returns the name of the Go type of v.
+// If nullable is true, it returns the type that is nullable.
+func (g *generator) typeName(v interface{}, nullable bool) string {
+	t := reflect.TypeOf(v)
+	if nullable && g.nullTypes {
+		switch v.(type) {
+		case bool:
+			t = reflect.TypeOf(sql.NullBool{})
+		case int64:
+			t = reflect.TypeOf(sql.NullInt64{})
+		case string:
+			t = reflect.TypeOf(sql.NullString{})
+		}
+	}
+	if t.Kind() == reflect.Slice {
+		return "[]byte"
+	}
+	if t.PkgPath() != "" {
+		g.imports[t.PkgPath()] = true
+	}
+	if nullable && t.PkgPath() != "database/sql" {
+		return "*" + t.String()
+	}
+	return t.String()
+}
+
+// guessGoType returns the zero value of the Go type that is guessed from the
+// name of the SQL type typ.
+func guessGoType(typ string) interface{} {
+	typ = strings.ToLower(typ)
+	switch {
+	case strings.Contains(typ, "bool"):
+		return false
+	case strings.Contains(typ, "int"):
+		return int64(0)
+	case strings.Contains(typ, "char"), strings.Contains(typ, "text"), strings.Contains(typ, "clob"):
+		return ""
+	case strings.Contains(typ, "date"), strings.Contains(typ, "time"):
+		return time.Time{}
+	case strings.Contains(typ, "dec"), strings.Contains(typ, "numeric"):
+		return Rat{}
+	case strings.Contains(typ, "real"), strings.Contains(typ, "floa"), strings.Contains(typ, "doub"):
+		return Float64(0)
+	}
+	return []byte(nil)
+}
+
+// mysqlDefaultExprRegexp matches the default values of MySQL that are
+// reported without quotes, but aren't the string literals.
+// e.g. "CURRENT_TIMESTAMP", "b'1'" and "curdate()"
+var mysqlDefaultExprRegexp = regexp.MustCompile(`(?i)^(NULL|TRUE|FALSE|CURRENT_TIMESTAMP(\(\d*\))?|b'[01]*'|[a-z_][a-z0-9_]*\(.*\)|\(.*\))$`)
+
+// defaultValue returns the value of "default" tag from the default value
+// that the database reports.
+// MySQL reports the string literal without quotes, so it is quoted unless
+// it is a number, a quoted literal or an expression.
+func (g *generator) defaultValue(def string) string {
+	if _, ok := g.db.dialect.(*MySQLDialect); !ok {
+		return def
+	}
+	if _, err := strconv.ParseFloat(def, 64); err == nil || strings.HasPrefix(def, "'") || mysqlDefaultExprRegexp.MatchString(def) {
+		return def
+	}
+	return "'" + strings.NewReplacer(`\`, `\\`, `'`, `''`).Replace(def) + "'"
+}
+
+// isSequenceDefault returns whether def is the default value of the
+// auto-incremented column. e.g. "nextval('user_id_seq'::regclass)"
+func isSequenceDefault(def string) bool {
+	return strings.HasPrefix(def, "nextval(")
+}
+
+// goIdentifier returns the exported Go identifier from the name of a table
+// or a column.
+func goIdentifier(name string) string {
+	name = strings.Map(func(r rune) rune {
+		if unicode.IsLetter(r) || unicode.IsDigit(r) || r == '_' {
+			return r
+		}
+		return '_'
+	}, name)
+	name = stringutil.ToUpperCamelCase(name)
+	if name == "" || !unicode.IsLetter([]rune(name)[0]) {
+		name = "X" + name
+	}
+	return name
+}
@@ -0,0 +1,202 @@
+package genmai
+
+import (
+	"bytes"
+	"testing"
+)
+
+type generateUser struct {
+	Id    int64 `db:"pk"`
+	Name  string
+	Email *string `db:"unique"`
+}
+
+func TestDB_Generate(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS generate_user`); err != nil {
+		t.Fatal(err)
+	}
+	defer db.db.Exec(`DROP TABLE IF EXISTS generate_user`)
+	if err := db.CreateTable(&generateUser{}); err != nil {
+		t.Fatal(err)
+	}
+	opts := &GenerateOptions{Tables: []string{"generate_user"}}
+	var buf bytes.Buffer
+	if err := db.Generate(&buf, opts); err != nil {
+		t.Fatal(err)
+	}
+	expect := `package models
+
+// GenerateUser represents the table "generate_user".
+type GenerateUser struct {
+	ID    int64 ` + "`" + `db:"pk"` + "`" + `
+	Name  string
+	Email *string ` + "`" + `db:"unique"` + "`" + `
+}
+`
+	if actual := buf.String(); actual != expect {
+		t.Errorf("DB.Generate(w, %#v) =>\n%s\nwant\n%s", opts, actual, expect)
+	}
+	if err := db.Generate(&bytes.Buffer{}, &GenerateOptions{Tables: []string{"not_exists"}}); err == nil {
+		t.Errorf("DB.Generate(w, not_exists) => nil; want error")
+	}
+}
+
+func TestDB_Generate_sqlite3(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, ok := db.dialect.(*SQLite3Dialect); !ok {
+		t.Skip("the tables are created by the DDL and the SQL types of SQLite3")
+	}
+	for _, query := range []string{
+		`CREATE TABLE user (id integer PRIMARY KEY AUTOINCREMENT, name text NOT NULL, email text UNIQUE, active boolean NOT NULL DEFAULT 1, born_at datetime, balance numeric, score real NOT NULL, avatar blob)`,
+		`CREATE TABLE user_groups (user_id integer NOT NULL, group_id integer NOT NULL, "Role" varchar(10), PRIMARY KEY (user_id, group_id))`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	for _, v := range []struct {
+		opts   *GenerateOptions
+		expect string
+	}{
+		{nil, `package models
+
+import (
+	"time"
+
+	"github.com/naoina/genmai"
+)
+
+// User represents the table "user".
+type User struct {
+	ID      int64 ` + "`" + `db:"pk"` + "`" + `
+	Name    string
+	Email   *string ` + "`" + `db:"unique"` + "`" + `
+	Active  bool    ` + "`" + `default:"1"` + "`" + `
+	BornAt  *time.Time
+	Balance *genmai.Rat
+	Score   genmai.Float64
+	Avatar  []byte
+}
+
+// UserGroups represents the table "user_groups".
+type UserGroups struct {
+	UserID  int64   ` + "`" + `db:"pk"` + "`" + `
+	GroupID int64   ` + "`" + `db:"pk"` + "`" + `
+	Role    *string ` + "`" + `column:"Role"` + "`" + `
+}
+`},
+		{&GenerateOptions{Package: "db", Tables: []string{"user"}, NullTypes: true}, `package db
+
+import (
+	"database/sql"
+	"time"
+
+	"github.com/naoina/genmai"
+)
+
+// User represents the table "user".
+type User struct {
+	ID      int64 ` + "`" + `db:"pk"` + "`" + `
+	Name    string
+	Email   sql.NullString ` + "`" + `db:"unique"` + "`" + `
+	Active  bool           ` + "`" + `default:"1"` + "`" + `
+	BornAt  *time.Time
+	Balance *genmai.Rat
+	Score   genmai.Float64
+	Avatar  []byte
+}
+`},
+	} {
+		var buf bytes.Buffer
+		if err := db.Generate(&buf, v.opts); err != nil {
+			t.Fatal(err)
+		}
+		if actual, expect := buf.String(), v.expect; actual != expect {
+			t.Errorf("DB.Generate(w, %#v) =>\n%s\nwant\n%s", v.opts, actual, expect)
+		}
+	}
+}
+
+func Test_goIdentifier(t *testing.T) {
+	for _, v := range []struct {
+		name, expect string
+	}{
+		{"user", "User"},
+		{"user_id", "UserID"},
+		{"user-name", "UserName"},
+		{"1st", "X1st"},
+	} {
+		if actual := goIdentifier(v.name); actual != v.expect {
+			t.Errorf("goIdentifier(%q) => %q; want %q", v.name, actual, v.expect)
+		}
+	}
+}
+
+func Test_generator_goType(t *testing.T) {
+	for _, v := range []struct {
+		dialect Dialect
+		column  Column
+		typ     string
+		size    uint64
+	}{
+		{&SQLite3Dialect{}, Column{Type: "INTEGER"}, "int64", 0},
+		{&SQLite3Dialect{}, Column{Type: "varchar(10)", Nullable: true}, "*string", 0},
+		{&MySQLDialect{}, Column{Type: "tinyint(1)"}, "bool", 0},
+		{&MySQLDialect{}, Column{Type: "int(11)"}, "int", 0},
+		{&MySQLDialect{}, Column{Type: "bigint(20)", Nullable: true}, "*int64", 0},
+		{&MySQLDialect{}, Column{Type: "smallint(6)"}, "int16", 0},
+		{&MySQLDialect{}, Column{Type: "varchar(255)"}, "string", 0},
+		{&MySQLDialect{}, Column{Type: "varchar(100)"}, "string", 100},
+		{&MySQLDialect{}, Column{Type: "decimal(65,30)"}, "genmai.Rat", 0},
+		{&MySQLDialect{}, Column{Type: "datetime"}, "time.Time", 0},
+		{&MySQLDialect{}, Column{Type: "varbinary(255)", Nullable: true}, "[]byte", 0},
+		{&PostgresDialect{}, Column{Type: "integer"}, "int", 0},
+		{&PostgresDialect{}, Column{Type: "character varying(32)"}, "string", 32},
+		{&PostgresDialect{}, Column{Type: "timestamp with time zone", Nullable: true}, "*time.Time", 0},
+		{&PostgresDialect{}, Column{Type: "numeric(65, 30)"}, "genmai.Rat", 0},
+		{&PostgresDialect{}, Column{Type: "double precision"}, "genmai.Float64", 0},
+		{&PostgresDialect{}, Column{Type: "uuid"}, "[]byte", 0},
+	} {
+		g := &generator{db: &DB{dialect: v.dialect}, imports: map[string]bool{}}
+		typ, size := g.goType(v.column)
+		if typ != v.typ || size != v.size {
+			t.Errorf("%T: generator.goType(%#v) => %q, %v; want %q, %v", v.dialect, v.column, typ, size, v.typ, v.size)
+		}
+	}
+}
+
+func Test_generator_defaultValue(t *testing.T) {
+	for _, v := range []struct {
+		dialect Dialect
+		def     string
+		expect  string
+	}{
+		{&SQLite3Dialect{}, `'alice'`, `'alice'`},
+		{&PostgresDialect{}, `'alice'::character varying`, `'alice'::character varying`},
+		{&MySQLDialect{}, `alice`, `'alice'`},
+		{&MySQLDialect{}, `it's \n`, `'it''s \\n'`},
+		{&MySQLDialect{}, ``, `''`},
+		{&MySQLDialect{}, `'alice'`, `'alice'`},
+		{&MySQLDialect{}, `20`, `20`},
+		{&MySQLDialect{}, `-1.5`, `-1.5`},
+		{&MySQLDialect{}, `CURRENT_TIMESTAMP`, `CURRENT_TIMESTAMP`},
+		{&MySQLDialect{}, `CURRENT_TIMESTAMP(6)`, `CURRENT_TIMESTAMP(6)`},
+		{&MySQLDialect{}, `curdate()`, `curdate()`},
+		{&MySQLDialect{}, `b'1'`, `b'1'`},
+		{&MySQLDialect{}, `NULL`, `NULL`},
+	} {
+		g := &generator{db: &DB{dialect: v.dialect}, imports: map[string]bool{}}
+		if actual := g.defaultValue(v.def); actual != v.expect {
+			t.Errorf("%T: generator.defaultValue(%q) => %q; want %q", v.dialect, v.def, actual, v.expect)
+		}
+	}
+}
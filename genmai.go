@@ -6,29 +6,56 @@
 package genmai
 
 import (
+	"context"
 	"database/sql"
 	"errors"
 	"fmt"
 	"io"
+	"math/big"
 	"reflect"
 	"runtime"
 	"sort"
 	"strconv"
 	"strings"
-	"sync"
+	"sync/atomic"
 
 	"github.com/naoina/go-stringutil"
 )
 
-var ErrTxDone = errors.New("genmai: transaction hasn't been started or already committed or rolled back")
+var ErrTxDone = errors.New("genmai: transaction has already been committed or rolled back")
+
+// ErrStaleObject is returned by Update and Delete when the record has been
+// updated or deleted by others since it was fetched. It is detected by the
+// field that specified "version" struct tag.
+var ErrStaleObject = errors.New("genmai: stale object: the record has been updated or deleted")
+
+// ErrNoRows is returned by Select when the output is a struct and no row matched.
+var ErrNoRows = errors.New("genmai: no rows in result set")
+
+// savepointSeq is a sequence number for the name of savepoints.
+var savepointSeq uint64
 
 // DB represents a database object.
 type DB struct {
 	db      *sql.DB
 	dialect Dialect
-	tx      *sql.Tx
-	m       sync.Mutex
+	tx      *sql.Tx // not nil if the DB is bound to a transaction by Begin.
 	logger  logger
+
+	// not nil if the DB is bound to a transaction that is started by the SQL of Dialect.StartTransaction.
+	conn *sql.Conn
+
+	// maximum number of retries of Transaction.
+	txRetries int
+
+	// cache of prepared statements. nil if disabled.
+	stmts *stmtCache
+
+	// whether the queries are run without explicit prepared statements.
+	noPrepare bool
+
+	// whether the stored values are written back by "RETURNING" clause.
+	returning bool
 }
 
 // New returns a new DB.
@@ -45,7 +72,15 @@ func New(dialect Dialect, dsn string) (*DB, error) {
 // output argument must be pointer to a slice of struct. If not a pointer or not a slice of struct, It returns error.
 // The table name of the database will be determined from name of struct. e.g. If *[]ATableName passed to output argument, table name will be "a_table_name".
 // If args are not given, fetch the all data like "SELECT * FROM table" SQL.
-func (db *DB) Select(output interface{}, args ...interface{}) (err error) {
+// If output is pointer to a struct, Select fetches the first row into it with
+// "LIMIT 1", and returns ErrNoRows if no row matched.
+func (db *DB) Select(output interface{}, args ...interface{}) error {
+	return db.SelectContext(context.Background(), output, args...)
+}
+
+// SelectContext is like Select, but with context.
+// The query will be cancelled if ctx is done before it completes.
+func (db *DB) SelectContext(ctx context.Context, output interface{}, args ...interface{}) (err error) {
 	defer func() {
 		if e := recover(); e != nil {
 			buf := make([]byte, 4096)
@@ -60,14 +95,9 @@ func (db *DB) Select(output interface{}, args ...interface{}) (err error) {
 	for rv.Kind() == reflect.Ptr {
 		rv = rv.Elem()
 	}
-	var tableName string
-	for _, arg := range args {
-		if f, ok := arg.(*From); ok {
-			if tableName != "" {
-				return fmt.Errorf("Select: From statement specified more than once")
-			}
-			tableName = f.TableName
-		}
+	tableName, m, err := db.fromTable("Select", args)
+	if err != nil {
+		return err
 	}
 	var selectFunc selectFunc
 	ptrN := 0
@@ -81,9 +111,24 @@ func (db *DB) Select(output interface{}, args ...interface{}) (err error) {
 			return fmt.Errorf("Select: argument of slice must be slice of struct, but %v", rv.Type())
 		}
 		if tableName == "" {
-			tableName = db.tableName(t)
+			m = db.modelOf(t)
+			tableName = m.tableName
 		}
 		selectFunc = db.selectToSlice
+	case reflect.Struct:
+		if isScalarStruct(rv.Type()) {
+			if tableName == "" {
+				return fmt.Errorf("Select: From statement must be given if any Function is given")
+			}
+			selectFunc = db.selectToValue
+			break
+		}
+		if tableName == "" {
+			m = db.modelOf(rv.Type())
+			tableName = m.tableName
+		}
+		args = db.limitOne(args)
+		selectFunc = db.selectToStruct
 	case reflect.Invalid:
 		return fmt.Errorf("Select: nil pointer dereference")
 	default:
@@ -92,27 +137,15 @@ func (db *DB) Select(output interface{}, args ...interface{}) (err error) {
 		}
 		selectFunc = db.selectToValue
 	}
-	col, from, conditions, err := db.classify(tableName, args)
+	query, values, err := db.selectQuery(tableName, m, args)
 	if err != nil {
 		return err
 	}
-	queries := []string{`SELECT`, col, `FROM`, db.dialect.Quote(from)}
-	var values []interface{}
-	for _, cond := range conditions {
-		q, a := cond.build(0, false)
-		queries = append(queries, q...)
-		values = append(values, a...)
-	}
-	query := strings.Join(queries, " ")
-	stmt, err := db.prepare(query, values...)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-	rows, err := stmt.Query(values...)
+	rows, done, err := db.query(ctx, query, values...)
 	if err != nil {
 		return err
 	}
+	defer done()
 	defer rows.Close()
 	value, err := selectFunc(rows, rv.Type())
 	if err != nil {
@@ -130,7 +163,8 @@ func (db *DB) From(arg interface{}) *From {
 	if t.Kind() != reflect.Struct {
 		panic(fmt.Errorf("From: argument must be struct (or that pointer) type, got %v", t))
 	}
-	return &From{TableName: db.tableName(t)}
+	m := db.modelOf(t)
+	return &From{TableName: m.tableName, model: m}
 }
 
 // Where returns a new Condition of "WHERE" clause.
@@ -192,56 +226,69 @@ const (
 // CreateTable creates the table into database.
 // If table isn't direct/indirect struct, it returns error.
 func (db *DB) CreateTable(table interface{}) error {
-	return db.createTable(table, false)
+	return db.CreateTableContext(context.Background(), table)
+}
+
+// CreateTableContext is like CreateTable, but with context.
+func (db *DB) CreateTableContext(ctx context.Context, table interface{}) error {
+	return db.createTable(ctx, table, false)
 }
 
 // CreateTableIfNotExists creates the table into database if table isn't exists.
 // If table isn't direct/indirect struct, it returns error.
 func (db *DB) CreateTableIfNotExists(table interface{}) error {
-	return db.createTable(table, true)
+	return db.CreateTableIfNotExistsContext(context.Background(), table)
+}
+
+// CreateTableIfNotExistsContext is like CreateTableIfNotExists, but with context.
+func (db *DB) CreateTableIfNotExistsContext(ctx context.Context, table interface{}) error {
+	return db.createTable(ctx, table, true)
 }
 
-func (db *DB) createTable(table interface{}, ifNotExists bool) error {
-	_, t, tableName, err := db.tableValueOf("CreateTable", table)
+func (db *DB) createTable(ctx context.Context, table interface{}, ifNotExists bool) error {
+	_, m, err := db.tableValueOf("CreateTable", table)
 	if err != nil {
 		return err
 	}
-	fields, err := db.collectTableFields(t)
+	query, err := db.createTableQuery(m, ifNotExists)
 	if err != nil {
 		return err
 	}
+	if _, err := db.exec(ctx, query); err != nil {
+		return err
+	}
+	return nil
+}
+
+// createTableQuery returns "CREATE TABLE" SQL of m.
+func (db *DB) createTableQuery(m *model, ifNotExists bool) (string, error) {
+	fields, err := db.collectTableFields(m)
+	if err != nil {
+		return "", err
+	}
 	var query string
 	if ifNotExists {
 		query = "CREATE TABLE IF NOT EXISTS %s (%s)"
 	} else {
 		query = "CREATE TABLE %s (%s)"
 	}
-	query = fmt.Sprintf(query, db.dialect.Quote(tableName), strings.Join(fields, ", "))
-	stmt, err := db.prepare(query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-	if _, err := stmt.Exec(); err != nil {
-		return err
-	}
-	return nil
+	return fmt.Sprintf(query, db.dialect.Quote(m.tableName), strings.Join(fields, ", ")), nil
 }
 
 // DropTable removes the table from database.
 // If table isn't direct/indirect struct, it returns error.
 func (db *DB) DropTable(table interface{}) error {
-	_, _, tableName, err := db.tableValueOf("DropTable", table)
-	if err != nil {
-		return err
-	}
-	query := fmt.Sprintf("DROP TABLE %s", db.dialect.Quote(tableName))
-	stmt, err := db.prepare(query)
+	return db.DropTableContext(context.Background(), table)
+}
+
+// DropTableContext is like DropTable, but with context.
+func (db *DB) DropTableContext(ctx context.Context, table interface{}) error {
+	_, m, err := db.tableValueOf("DropTable", table)
 	if err != nil {
 		return err
 	}
-	defer stmt.Close()
-	if _, err = stmt.Exec(); err != nil {
+	query := fmt.Sprintf("DROP TABLE %s", db.dialect.Quote(m.tableName))
+	if _, err = db.exec(ctx, query); err != nil {
 		return err
 	}
 	return nil
@@ -250,20 +297,31 @@ func (db *DB) DropTable(table interface{}) error {
 // CreateIndex creates the index into database.
 // If table isn't direct/indirect struct, it returns error.
 func (db *DB) CreateIndex(table interface{}, name string, names ...string) error {
-	return db.createIndex(table, false, name, names...)
+	return db.CreateIndexContext(context.Background(), table, name, names...)
+}
+
+// CreateIndexContext is like CreateIndex, but with context.
+func (db *DB) CreateIndexContext(ctx context.Context, table interface{}, name string, names ...string) error {
+	return db.createIndex(ctx, table, false, name, names...)
 }
 
 // CreateUniqueIndex creates the unique index into database.
 // If table isn't direct/indirect struct, it returns error.
 func (db *DB) CreateUniqueIndex(table interface{}, name string, names ...string) error {
-	return db.createIndex(table, true, name, names...)
+	return db.CreateUniqueIndexContext(context.Background(), table, name, names...)
 }
 
-func (db *DB) createIndex(table interface{}, unique bool, name string, names ...string) error {
-	_, _, tableName, err := db.tableValueOf("CreateIndex", table)
+// CreateUniqueIndexContext is like CreateUniqueIndex, but with context.
+func (db *DB) CreateUniqueIndexContext(ctx context.Context, table interface{}, name string, names ...string) error {
+	return db.createIndex(ctx, table, true, name, names...)
+}
+
+func (db *DB) createIndex(ctx context.Context, table interface{}, unique bool, name string, names ...string) error {
+	_, m, err := db.tableValueOf("CreateIndex", table)
 	if err != nil {
 		return err
 	}
+	tableName := m.tableName
 	names = append([]string{name}, names...)
 	indexes := make([]string, len(names))
 	for i, name := range names {
@@ -280,12 +338,7 @@ func (db *DB) createIndex(table interface{}, unique bool, name string, names ...
 		db.dialect.Quote(indexName),
 		db.dialect.Quote(tableName),
 		strings.Join(indexes, ", "))
-	stmt, err := db.prepare(query)
-	if err != nil {
-		return err
-	}
-	defer stmt.Close()
-	if _, err := stmt.Exec(); err != nil {
+	if _, err := db.exec(ctx, query); err != nil {
 		return err
 	}
 	return nil
@@ -295,8 +348,41 @@ func (db *DB) createIndex(table interface{}, unique bool, name string, names ...
 // The obj must be struct, and must have field that specified "pk" struct tag.
 // Update will try to update record which searched by value of primary key in obj.
 // Update returns the number of rows affected by an update.
+// If obj embeds DirtyTracker and has a snapshot, Update writes only the
+// changed columns, and returns 0 without query if nothing changed.
 func (db *DB) Update(obj interface{}) (affected int64, err error) {
-	rv, rtype, tableName, err := db.tableValueOf("Update", obj)
+	return db.UpdateContext(context.Background(), obj)
+}
+
+// UpdateContext is like Update, but with context.
+func (db *DB) UpdateContext(ctx context.Context, obj interface{}) (affected int64, err error) {
+	return db.update(ctx, "Update", obj, nil)
+}
+
+// UpdateColumns is like Update, but it updates only the given columns.
+// columns are the column names, not the field names.
+// If obj embeds DirtyTracker, the snapshot of only the given columns is
+// refreshed, so the changes of the other fields will be written by the next
+// Update.
+//
+//     db.UpdateColumns(&user, "name", "updated_at")
+func (db *DB) UpdateColumns(obj interface{}, columns ...string) (affected int64, err error) {
+	return db.UpdateColumnsContext(context.Background(), obj, columns...)
+}
+
+// UpdateColumnsContext is like UpdateColumns, but with context.
+func (db *DB) UpdateColumnsContext(ctx context.Context, obj interface{}, columns ...string) (affected int64, err error) {
+	if len(columns) < 1 {
+		return -1, fmt.Errorf("UpdateColumns: no columns given")
+	}
+	return db.update(ctx, "UpdateColumns", obj, columns)
+}
+
+// update updates the one record.
+// If columns is nil, it updates the columns of all fields, or the changed
+// fields if obj is dirty-tracked.
+func (db *DB) update(ctx context.Context, name string, obj interface{}, columns []string) (affected int64, err error) {
+	rv, m, err := db.tableValueOf(name, obj)
 	if err != nil {
 		return -1, err
 	}
@@ -305,34 +391,76 @@ func (db *DB) Update(obj interface{}) (affected int64, err error) {
 			return -1, err
 		}
 	}
-	fieldIndexes := db.collectFieldIndexes(rtype, nil)
-	pkIdx := db.findPKIndex(rtype, nil)
-	if len(pkIdx) < 1 {
-		return -1, fmt.Errorf(`Update: fields of struct doesn't have primary key: "pk" struct tag must be specified for update`)
+	if len(m.pks) < 1 {
+		return -1, fmt.Errorf(`%s: fields of struct doesn't have primary key: "pk" struct tag must be specified for update`, name)
 	}
-	sets := make([]string, len(fieldIndexes))
+	fields := m.valueFields
+	if columns != nil {
+		if fields, err = db.fieldsByColumns(name, m, columns); err != nil {
+			return -1, err
+		}
+	} else if dt := dirtyTrackerOf(rv); dt != nil && dt.snapshot != nil {
+		if fields = db.changedFields(m, rv, dt); len(fields) < 1 {
+			return 0, nil
+		}
+	}
+	var sets []string
 	var args []interface{}
-	for i, index := range fieldIndexes {
-		col := db.columnFromTag(rtype.FieldByIndex(index))
-		sets[i] = fmt.Sprintf("%s = %s", db.dialect.Quote(col), db.dialect.PlaceHolder(i))
-		args = append(args, rv.FieldByIndex(index).Interface())
+	for _, f := range fields {
+		if f == m.version {
+			continue
+		}
+		sets = append(sets, fmt.Sprintf("%s = %s", db.dialect.Quote(f.column), db.dialect.PlaceHolder(len(args))))
+		args = append(args, rv.FieldByIndex(f.index).Interface())
 	}
-	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s = %s",
-		db.dialect.Quote(tableName),
+	var nextVersion reflect.Value
+	if m.version != nil {
+		if nextVersion, err = incrementVersion(rv.FieldByIndex(m.version.index)); err != nil {
+			return -1, fmt.Errorf("%s: %v", name, err)
+		}
+		sets = append(sets, fmt.Sprintf("%s = %s", db.dialect.Quote(m.version.column), db.dialect.PlaceHolder(len(args))))
+		args = append(args, nextVersion.Interface())
+	}
+	wheres := make([]string, len(m.keys))
+	for i, f := range m.keys {
+		wheres[i] = fmt.Sprintf("%s = %s", db.dialect.Quote(f.column), db.dialect.PlaceHolder(len(args)))
+		args = append(args, rv.FieldByIndex(f.index).Interface())
+	}
+	query := fmt.Sprintf("UPDATE %s SET %s WHERE %s",
+		db.dialect.Quote(m.tableName),
 		strings.Join(sets, ", "),
-		db.dialect.Quote(db.columnFromTag(rtype.FieldByIndex(pkIdx))),
-		db.dialect.PlaceHolder(len(fieldIndexes)))
-	args = append(args, rv.FieldByIndex(pkIdx).Interface())
-	stmt, err := db.prepare(query, args...)
-	if err != nil {
-		return -1, err
+		strings.Join(wheres, " AND "))
+	if returning := db.returningClause(ctx, m); returning != "" {
+		query = fmt.Sprintf("%s %s", query, returning)
+		affected, err = db.queryReturning(ctx, m, m.fields, query, args, func(i int, row reflect.Value) {
+			writeBack(m, rv, row)
+		})
+		if err != nil {
+			return affected, err
+		}
+	} else {
+		result, err := db.exec(ctx, query, args...)
+		if err != nil {
+			return -1, err
+		}
+		affected, _ = result.RowsAffected()
 	}
-	defer stmt.Close()
-	result, err := stmt.Exec(args...)
-	if err != nil {
-		return -1, err
+	if m.version != nil {
+		if affected < 1 {
+			return affected, ErrStaleObject
+		}
+		if v := rv.FieldByIndex(m.version.index); v.CanSet() {
+			v.Set(nextVersion)
+		}
+	}
+	if columns != nil {
+		if m.version != nil {
+			fields = append(fields, m.version)
+		}
+		db.refreshSnapshot(rv, fields)
+	} else {
+		db.takeSnapshot(m, rv)
 	}
-	affected, _ = result.RowsAffected()
 	if hook, ok := obj.(AfterUpdater); ok {
 		if err := hook.AfterUpdate(); err != nil {
 			return affected, err
@@ -341,37 +469,166 @@ func (db *DB) Update(obj interface{}) (affected int64, err error) {
 	return affected, nil
 }
 
-// Insert inserts one or more records to the database table.
-// The obj must be pointer to struct or slice of struct. If a struct have a
-// field which specified "pk" struct tag on type of autoincrementable, it
-// won't be used to as an insert value.
-// Insert sets the last inserted id to the primary key of the instance of the given obj if obj is single.
-// Insert returns the number of rows affected by insert.
-func (db *DB) Insert(obj interface{}) (affected int64, err error) {
-	objs, rtype, tableName, err := db.tableObjs("Insert", obj)
+// insertInChunks inserts objs by one or more statements so that the number
+// of parameters of a statement doesn't exceed Dialect.MaxParameters.
+// If objs are split, they will be inserted in a transaction unless the DB is
+// already bound to a transaction.
+func (db *DB) insertInChunks(ctx context.Context, m *model, objs []interface{}) (affected int64, err error) {
+	return db.inChunks(ctx, m, objs, func(db *DB, objs []interface{}) (int64, error) {
+		return db.insert(ctx, m, objs)
+	})
+}
+
+// inChunks calls fn with the chunks of objs so that the number of parameters
+// of the values of a chunk doesn't exceed Dialect.MaxParameters, and returns
+// the sum of the affected rows.
+// If objs are split, fn will be called in a transaction unless the DB is
+// already bound to a transaction.
+func (db *DB) inChunks(ctx context.Context, m *model, objs []interface{}, fn func(db *DB, objs []interface{}) (int64, error)) (affected int64, err error) {
+	size := len(objs)
+	if max := db.dialect.MaxParameters(); max > 0 && len(m.valueFields) > 0 {
+		if size = max / len(m.valueFields); size < 1 {
+			size = 1
+		}
+	}
+	if len(objs) <= size {
+		return fn(db, objs)
+	}
+	if !db.inTx() {
+		err := db.TransactionContext(ctx, nil, func(tx *Tx) (err error) {
+			affected, err = tx.inChunks(ctx, m, objs, fn)
+			return err
+		})
+		return affected, err
+	}
+	for i := 0; i < len(objs); i += size {
+		end := i + size
+		if end > len(objs) {
+			end = len(objs)
+		}
+		n, err := fn(db, objs[i:end])
+		if err != nil {
+			return affected, err
+		}
+		affected += n
+	}
+	return affected, nil
+}
+
+// insert inserts objs by a statement, and sets the auto-incremented IDs or
+// the returned values to objs.
+func (db *DB) insert(ctx context.Context, m *model, objs []interface{}) (affected int64, err error) {
+	if returning := db.returningClause(ctx, m); returning != "" {
+		return db.insertReturning(ctx, m, objs, m.fields, func(rv, row reflect.Value) {
+			writeBack(m, rv, row)
+		})
+	}
+	if pk := m.autoIncrementPK(); pk != nil {
+		return db.insertWithIDs(ctx, m, pk, objs)
+	}
+	query, args := db.insertQuery(m, objs)
+	result, err := db.exec(ctx, query, args...)
 	if err != nil {
 		return -1, err
 	}
-	if len(objs) < 1 {
-		return 0, nil
-	}
-	for _, obj := range objs {
-		if hook, ok := obj.(BeforeInserter); ok {
-			if err := hook.BeforeInsert(); err != nil {
+	affected, _ = result.RowsAffected()
+	return affected, nil
+}
+
+// insertWithIDs inserts objs, and sets the auto-incremented IDs to the
+// primary key of objs in the way of Dialect.BulkInsertIDStrategy.
+func (db *DB) insertWithIDs(ctx context.Context, m *model, pk *modelField, objs []interface{}) (affected int64, err error) {
+	switch strategy := db.dialect.BulkInsertIDStrategy(); strategy {
+	case InsertIDReturning:
+		return db.insertReturning(ctx, m, objs, []*modelField{pk}, func(rv, row reflect.Value) {
+			if id := rv.FieldByIndex(pk.index); id.CanSet() {
+				id.Set(row.FieldByIndex(pk.index))
+			}
+		})
+	case InsertIDFirstPlusOffset, InsertIDLastMinusOffset:
+		query, args := db.insertQuery(m, objs)
+		result, err := db.exec(ctx, query, args...)
+		if err != nil {
+			return -1, err
+		}
+		affected, _ = result.RowsAffected()
+		id, err := result.LastInsertId()
+		if err != nil {
+			return affected, err
+		}
+		if strategy == InsertIDLastMinusOffset {
+			id -= int64(len(objs) - 1)
+		}
+		for i, obj := range objs {
+			setID(obj, pk, id+int64(i))
+		}
+		return affected, nil
+	case InsertIDRowByRow:
+		if len(objs) > 1 && !db.inTx() {
+			err := db.TransactionContext(ctx, nil, func(tx *Tx) (err error) {
+				affected, err = tx.insertWithIDs(ctx, m, pk, objs)
+				return err
+			})
+			return affected, err
+		}
+		for _, obj := range objs {
+			query, args := db.insertQuery(m, []interface{}{obj})
+			result, err := db.exec(ctx, query, args...)
+			if err != nil {
 				return -1, err
 			}
+			n, _ := result.RowsAffected()
+			affected += n
+			id, err := result.LastInsertId()
+			if err != nil {
+				return affected, err
+			}
+			setID(obj, pk, id)
 		}
+		return affected, nil
+	default:
+		return -1, fmt.Errorf("Insert: unknown InsertIDStrategy: %v", strategy)
 	}
-	fieldIndexes := db.collectFieldIndexes(rtype, nil)
-	cols := make([]string, len(fieldIndexes))
-	for i, index := range fieldIndexes {
-		cols[i] = db.dialect.Quote(db.columnFromTag(rtype.FieldByIndex(index)))
+}
+
+// incrementVersion returns the value of v plus one.
+// v must be the value of integer type.
+func incrementVersion(v reflect.Value) (reflect.Value, error) {
+	next := reflect.New(v.Type()).Elem()
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		next.SetInt(v.Int() + 1)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		next.SetUint(v.Uint() + 1)
+	default:
+		return reflect.Value{}, fmt.Errorf(`"version" tag must be specified to integer field, got %v`, v.Type())
+	}
+	return next, nil
+}
+
+// setID sets id to the primary key field of obj.
+// If obj isn't addressable, it does nothing.
+func setID(obj interface{}, pk *modelField, id int64) {
+	rv := reflect.Indirect(reflect.ValueOf(obj)).FieldByIndex(pk.index)
+	for rv.Kind() == reflect.Ptr {
+		rv = rv.Elem()
+	}
+	if !rv.CanSet() {
+		return
+	}
+	rv.Set(reflect.ValueOf(id).Convert(rv.Type()))
+}
+
+// insertQuery returns the "INSERT" query of objs and its arguments.
+func (db *DB) insertQuery(m *model, objs []interface{}) (query string, args []interface{}) {
+	cols := make([]string, len(m.valueFields))
+	for i, f := range m.valueFields {
+		cols[i] = db.dialect.Quote(f.column)
 	}
-	var args []interface{}
 	for _, obj := range objs {
 		rv := reflect.Indirect(reflect.ValueOf(obj))
-		for _, index := range fieldIndexes {
-			args = append(args, rv.FieldByIndex(index).Interface())
+		for _, f := range m.valueFields {
+			args = append(args, rv.FieldByIndex(f.index).Interface())
 		}
 	}
 	numHolders := 0
@@ -384,37 +641,138 @@ func (db *DB) Insert(obj interface{}) (affected int64, err error) {
 		}
 		values[i] = fmt.Sprintf("(%s)", strings.Join(holders, ", "))
 	}
-	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
-		db.dialect.Quote(tableName),
+	query = fmt.Sprintf("INSERT INTO %s (%s) VALUES %s",
+		db.dialect.Quote(m.tableName),
 		strings.Join(cols, ", "),
 		strings.Join(values, ", "),
 	)
-	stmt, err := db.prepare(query, args...)
-	if err != nil {
-		return -1, err
+	return query, args
+}
+
+// fieldsByColumns returns the fields of m corresponding to the columns.
+// A column of the auto-incrementable primary key cannot be specified.
+func (db *DB) fieldsByColumns(name string, m *model, columns []string) ([]*modelField, error) {
+	fields := make([]*modelField, len(columns))
+	for i, column := range columns {
+		for _, f := range m.valueFields {
+			if f.column == column {
+				fields[i] = f
+				break
+			}
+		}
+		if fields[i] == nil {
+			return nil, fmt.Errorf("%s: column `%v` isn't defined in %v or cannot be updated", name, column, m.typ)
+		}
 	}
-	defer stmt.Close()
-	result, err := stmt.Exec(args...)
+	return fields, nil
+}
+
+// dirtyTrackerOf returns the DirtyTracker embedded in rv.
+// If rv doesn't embed it or isn't addressable, it returns nil.
+func dirtyTrackerOf(rv reflect.Value) *DirtyTracker {
+	if !rv.CanAddr() {
+		return nil
+	}
+	if t, ok := rv.Addr().Interface().(dirtyTracked); ok {
+		return t.dirtyTracker()
+	}
+	return nil
+}
+
+// takeSnapshot saves the values of the fields of rv if rv is dirty-tracked.
+func (db *DB) takeSnapshot(m *model, rv reflect.Value) {
+	dt := dirtyTrackerOf(rv)
+	if dt == nil {
+		return
+	}
+	dt.snapshot = make(map[string]interface{}, len(m.fields))
+	for _, f := range m.fields {
+		dt.snapshot[f.column] = snapshotValue(rv, f)
+	}
+}
+
+// refreshSnapshot updates the snapshot of only fields to the values of rv
+// if rv is dirty-tracked and has a snapshot. The other fields are left as
+// is, so that their changes will be written by the next Update.
+func (db *DB) refreshSnapshot(rv reflect.Value, fields []*modelField) {
+	dt := dirtyTrackerOf(rv)
+	if dt == nil || dt.snapshot == nil {
+		return
+	}
+	for _, f := range fields {
+		dt.snapshot[f.column] = snapshotValue(rv, f)
+	}
+}
+
+// snapshotValue returns the value of the field f of rv for the snapshot.
+func snapshotValue(rv reflect.Value, f *modelField) interface{} {
+	return deepCopy(rv.FieldByIndex(f.index)).Interface()
+}
+
+// deepCopy returns a copy of v that doesn't share the value behind the
+// pointers and the bytes with v, to detect the in-place changes of them.
+func deepCopy(v reflect.Value) reflect.Value {
+	if r, ok := v.Interface().(Rat); ok {
+		if r.Rat != nil {
+			r.Rat = new(big.Rat).Set(r.Rat)
+		}
+		return reflect.ValueOf(r)
+	}
+	switch {
+	case v.Kind() == reflect.Ptr && !v.IsNil():
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(deepCopy(v.Elem()))
+		return c
+	case v.Kind() == reflect.Slice && v.Type().Elem().Kind() == reflect.Uint8 && !v.IsNil():
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		reflect.Copy(c, v)
+		return c
+	}
+	return v
+}
+
+// changedFields returns the fields that have been changed since the snapshot.
+func (db *DB) changedFields(m *model, rv reflect.Value, dt *DirtyTracker) (fields []*modelField) {
+	for _, f := range m.valueFields {
+		if !reflect.DeepEqual(rv.FieldByIndex(f.index).Interface(), dt.snapshot[f.column]) {
+			fields = append(fields, f)
+		}
+	}
+	return fields
+}
+
+// Insert inserts one or more records to the database table.
+// The obj must be pointer to struct or slice of struct. If a struct have a
+// field which specified "pk" struct tag on type of autoincrementable, it
+// won't be used to as an insert value.
+// Insert sets the last inserted id to the primary key of the instance of the given obj if obj is single.
+// Insert returns the number of rows affected by insert.
+func (db *DB) Insert(obj interface{}) (affected int64, err error) {
+	return db.InsertContext(context.Background(), obj)
+}
+
+// InsertContext is like Insert, but with context.
+func (db *DB) InsertContext(ctx context.Context, obj interface{}) (affected int64, err error) {
+	objs, m, err := db.tableObjs("Insert", obj)
 	if err != nil {
 		return -1, err
 	}
-	affected, _ = result.RowsAffected()
-	if len(objs) == 1 {
-		if pkIdx := db.findPKIndex(rtype, nil); len(pkIdx) > 0 {
-			field := rtype.FieldByIndex(pkIdx)
-			if db.isAutoIncrementable(&field) {
-				id, err := db.LastInsertId()
-				if err != nil {
-					return affected, err
-				}
-				rv := reflect.Indirect(reflect.ValueOf(objs[0])).FieldByIndex(pkIdx)
-				for rv.Kind() == reflect.Ptr {
-					rv = rv.Elem()
-				}
-				rv.Set(reflect.ValueOf(id).Convert(rv.Type()))
+	if len(objs) < 1 {
+		return 0, nil
+	}
+	for _, obj := range objs {
+		if hook, ok := obj.(BeforeInserter); ok {
+			if err := hook.BeforeInsert(); err != nil {
+				return -1, err
 			}
 		}
 	}
+	if affected, err = db.insertInChunks(ctx, m, objs); err != nil {
+		return affected, err
+	}
+	for _, obj := range objs {
+		db.takeSnapshot(m, reflect.Indirect(reflect.ValueOf(obj)))
+	}
 	for _, obj := range objs {
 		if hook, ok := obj.(AfterInserter); ok {
 			if err := hook.AfterInsert(); err != nil {
@@ -425,108 +783,352 @@ func (db *DB) Insert(obj interface{}) (affected int64, err error) {
 	return affected, nil
 }
 
-// Delete deletes the records from database table.
-// The obj must be pointer to struct or slice of struct, and must have field that specified "pk" struct tag.
-// Delete will try to delete record which searched by value of primary key in obj.
-// Delete returns teh number of rows affected by a delete.
-func (db *DB) Delete(obj interface{}) (affected int64, err error) {
-	objs, rtype, tableName, err := db.tableObjs("Delete", obj)
+// Delete deletes the records from database table.
+// The obj must be pointer to struct or slice of struct, and must have field that specified "pk" struct tag.
+// Delete will try to delete record which searched by value of primary key in obj.
+// If the struct has a field that specified "softdelete" struct tag, Delete
+// sets the current time to it instead of deleting the records.
+// Delete returns teh number of rows affected by a delete.
+func (db *DB) Delete(obj interface{}) (affected int64, err error) {
+	return db.DeleteContext(context.Background(), obj)
+}
+
+// DeleteContext is like Delete, but with context.
+func (db *DB) DeleteContext(ctx context.Context, obj interface{}) (affected int64, err error) {
+	return db.delete(ctx, "Delete", obj, false)
+}
+
+// delete deletes the records of obj.
+// If hard is false and the records can be soft-deleted, they will be soft-deleted.
+func (db *DB) delete(ctx context.Context, name string, obj interface{}, hard bool) (affected int64, err error) {
+	objs, m, err := db.tableObjs(name, obj)
+	if err != nil {
+		return -1, err
+	}
+	if len(objs) < 1 {
+		return 0, nil
+	}
+	for _, obj := range objs {
+		if hook, ok := obj.(BeforeDeleter); ok {
+			if err := hook.BeforeDelete(); err != nil {
+				return -1, err
+			}
+		}
+	}
+	if len(m.pks) < 1 {
+		return -1, fmt.Errorf(`%s: fields of struct doesn't have primary key: "pk" struct tag must be specified for delete`, name)
+	}
+	if m.softDelete != nil && !hard {
+		if affected, err = db.softDelete(ctx, m, objs); err != nil {
+			return affected, err
+		}
+	} else if affected, err = db.hardDelete(ctx, m, objs); err != nil {
+		return affected, err
+	}
+	if m.version != nil && affected < int64(len(objs)) {
+		return affected, ErrStaleObject
+	}
+	for _, obj := range objs {
+		if hook, ok := obj.(AfterDeleter); ok {
+			if err := hook.AfterDelete(); err != nil {
+				return affected, err
+			}
+		}
+	}
+	return affected, nil
+}
+
+// hardDelete deletes the records of objs by "DELETE" query.
+func (db *DB) hardDelete(ctx context.Context, m *model, objs []interface{}) (affected int64, err error) {
+	where, args := db.keyPredicate(0, m.keys, objs)
+	query := fmt.Sprintf("DELETE FROM %s WHERE %s", db.dialect.Quote(m.tableName), where)
+	if returning := db.returningClause(ctx, m); returning != "" {
+		query = fmt.Sprintf("%s %s", query, returning)
+		// the order of the deleted rows is undefined.
+		objsByPK := make(map[string]reflect.Value, len(objs))
+		for _, obj := range objs {
+			rv := reflect.Indirect(reflect.ValueOf(obj))
+			objsByPK[pkKey(m, rv)] = rv
+		}
+		affected, err = db.queryReturning(ctx, m, m.fields, query, args, func(i int, row reflect.Value) {
+			if rv, ok := objsByPK[pkKey(m, row)]; ok {
+				writeBack(m, rv, row)
+			}
+		})
+		if err != nil {
+			return affected, err
+		}
+	} else {
+		result, err := db.exec(ctx, query, args...)
+		if err != nil {
+			return -1, err
+		}
+		affected, _ = result.RowsAffected()
+	}
+	return affected, nil
+}
+
+// UpdateWhere updates the records of the table that match the cond.
+// table must be struct or pointer to struct, and the table name will be
+// determined from it.
+// sets must be map[string]interface{} of column names and values, or struct.
+// If sets is struct, the columns of its non-zero fields will be updated.
+// Use map if you want to set the zero value.
+// cond must be given and have "WHERE" clause to prevent updating all records
+// by mistake. cond won't be modified.
+// UpdateWhere returns the number of rows affected by an update.
+//
+//     db.UpdateWhere(&User{}, map[string]interface{}{"active": false}, db.Where("id").In(1, 2, 3))
+func (db *DB) UpdateWhere(table interface{}, sets interface{}, cond *Condition) (affected int64, err error) {
+	return db.UpdateWhereContext(context.Background(), table, sets, cond)
+}
+
+// UpdateWhereContext is like UpdateWhere, but with context.
+func (db *DB) UpdateWhereContext(ctx context.Context, table interface{}, sets interface{}, cond *Condition) (affected int64, err error) {
+	_, m, err := db.tableValueOf("UpdateWhere", table)
+	if err != nil {
+		return -1, err
+	}
+	if cond, err = whereCondition("UpdateWhere", m, cond); err != nil {
+		return -1, err
+	}
+	columns, values, err := db.columnValues("UpdateWhere", sets)
+	if err != nil {
+		return -1, err
+	}
+	if len(columns) < 1 {
+		return 0, nil
+	}
+	assigns := make([]string, len(columns))
+	for i, column := range columns {
+		assigns[i] = fmt.Sprintf("%s = %s", db.dialect.Quote(column), db.dialect.PlaceHolder(i))
+	}
+	q, a := cond.build(len(values), false)
+	query := strings.Join(append([]string{
+		"UPDATE", db.dialect.Quote(m.tableName), "SET", strings.Join(assigns, ", "),
+	}, q...), " ")
+	result, err := db.exec(ctx, query, append(values, a...)...)
+	if err != nil {
+		return -1, err
+	}
+	affected, _ = result.RowsAffected()
+	return affected, nil
+}
+
+// DeleteWhere deletes the records of the table that match the cond.
+// table must be struct or pointer to struct, and the table name will be
+// determined from it.
+// cond must be given and have "WHERE" clause to prevent deleting all records
+// by mistake. cond won't be modified.
+// If the struct has a field that specified "softdelete" struct tag, the
+// records will be soft-deleted as well as Delete. Use HardDeleteWhere to
+// delete them physically.
+// DeleteWhere returns the number of rows affected by a delete.
+//
+//     db.DeleteWhere(&User{}, db.Where("created_at", "<", t))
+func (db *DB) DeleteWhere(table interface{}, cond *Condition) (affected int64, err error) {
+	return db.DeleteWhereContext(context.Background(), table, cond)
+}
+
+// DeleteWhereContext is like DeleteWhere, but with context.
+func (db *DB) DeleteWhereContext(ctx context.Context, table interface{}, cond *Condition) (affected int64, err error) {
+	return db.deleteWhere(ctx, "DeleteWhere", table, cond, false)
+}
+
+// deleteWhere deletes the records of the table that match the cond.
+// If hard is false and the table has the soft delete column, the records
+// will be soft-deleted.
+func (db *DB) deleteWhere(ctx context.Context, name string, table interface{}, cond *Condition, hard bool) (affected int64, err error) {
+	_, m, err := db.tableValueOf(name, table)
 	if err != nil {
 		return -1, err
 	}
-	if len(objs) < 1 {
-		return 0, nil
+	if cond, err = whereCondition(name, m, cond); err != nil {
+		return -1, err
 	}
-	for _, obj := range objs {
-		if hook, ok := obj.(BeforeDeleter); ok {
-			if err := hook.BeforeDelete(); err != nil {
-				return -1, err
-			}
+	if m.softDelete != nil && !hard {
+		return db.softDeleteWhere(ctx, name, m, cond)
+	}
+	q, a := cond.build(0, false)
+	query := strings.Join(append([]string{"DELETE FROM", db.dialect.Quote(m.tableName)}, q...), " ")
+	result, err := db.exec(ctx, query, a...)
+	if err != nil {
+		return -1, err
+	}
+	affected, _ = result.RowsAffected()
+	return affected, nil
+}
+
+// whereCondition returns the copy of cond for the table of m.
+// It returns an error if cond is nil or doesn't have "WHERE" clause.
+func whereCondition(name string, m *model, cond *Condition) (*Condition, error) {
+	if cond == nil {
+		return nil, fmt.Errorf("%s: condition must be given", name)
+	}
+	for _, p := range cond.parts {
+		if p.clause == Where {
+			return &Condition{db: cond.db, parts: append(parts(nil), cond.parts...), tableName: m.tableName}, nil
 		}
 	}
-	pkIdx := db.findPKIndex(rtype, nil)
-	if len(pkIdx) < 1 {
-		return -1, fmt.Errorf(`Delete: fields of struct doesn't have primary key: "pk" struct tag must be specified for delete`)
+	return nil, fmt.Errorf("%s: condition must have WHERE clause", name)
+}
+
+// columnValues returns the column names and the values from sets.
+// sets must be map[string]interface{} or struct (or pointer to struct).
+// If sets is struct, only non-zero fields will be returned.
+// The columns of map will be sorted to build the same query every time.
+func (db *DB) columnValues(name string, sets interface{}) (columns []string, values []interface{}, err error) {
+	switch s := sets.(type) {
+	case map[string]interface{}:
+		for column := range s {
+			columns = append(columns, column)
+		}
+		sort.Strings(columns)
+		for _, column := range columns {
+			values = append(values, s[column])
+		}
+		return columns, values, nil
 	}
-	var args []interface{}
-	for _, obj := range objs {
-		rv := reflect.Indirect(reflect.ValueOf(obj))
-		args = append(args, rv.FieldByIndex(pkIdx).Interface())
+	rv := reflect.Indirect(reflect.ValueOf(sets))
+	if rv.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("%s: sets must be map[string]interface{} or struct, got %T", name, sets)
 	}
-	holders := make([]string, len(args))
-	for i := 0; i < len(holders); i++ {
-		holders[i] = db.dialect.PlaceHolder(i)
+	for _, f := range db.modelOf(rv.Type()).valueFields {
+		v := rv.FieldByIndex(f.index).Interface()
+		if reflect.DeepEqual(v, reflect.Zero(f.field.Type).Interface()) {
+			continue
+		}
+		columns = append(columns, f.column)
+		values = append(values, v)
 	}
-	query := fmt.Sprintf("DELETE FROM %s WHERE %s IN (%s)",
-		db.dialect.Quote(tableName),
-		db.dialect.Quote(db.columnFromTag(rtype.FieldByIndex(pkIdx))),
-		strings.Join(holders, ", "))
-	stmt, err := db.prepare(query, args...)
-	if err != nil {
-		return -1, err
+	return columns, values, nil
+}
+
+// Begin starts a transaction and returns it.
+// The DB itself isn't affected, so a DB can be shared by goroutines that
+// run their own transactions.
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction with context and returns it.
+// opts may be nil. If the isolation level or read-only mode of opts need
+// explicit SQL for the database, the transaction will be started by the SQL
+// of Dialect.StartTransaction. Otherwise opts is passed to the database/sql.
+// In both cases, the transaction will be rolled back if ctx is done before
+// Commit is called.
+//
+// If the DB is already bound to a transaction (i.e. Begin of Tx is called),
+// BeginTx starts a nested transaction by using a savepoint.
+// Options can't be specified to the nested transaction.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	if db.inTx() {
+		if opts != nil {
+			return nil, fmt.Errorf("BeginTx: options can't be specified to the nested transaction")
+		}
+		name := fmt.Sprintf("genmai_savepoint_%d", atomic.AddUint64(&savepointSeq, 1))
+		if _, err := db.execDirect(ctx, db.dialect.SavePoint(name)); err != nil {
+			return nil, err
+		}
+		return &Tx{txDB: db.withTx(db.tx, db.conn), ctx: ctx, savepoint: name}, nil
 	}
-	defer stmt.Close()
-	result, err := stmt.Exec(args...)
+	queries, err := db.dialect.StartTransaction(opts)
 	if err != nil {
-		return -1, err
+		return nil, err
 	}
-	affected, _ = result.RowsAffected()
-	for _, obj := range objs {
-		if hook, ok := obj.(AfterDeleter); ok {
-			if err := hook.AfterDelete(); err != nil {
-				return affected, err
+	if len(queries) > 0 {
+		conn, err := db.db.Conn(ctx)
+		if err != nil {
+			return nil, err
+		}
+		tx := &Tx{txDB: db.withTx(nil, conn), ctx: ctx, finished: make(chan struct{})}
+		for _, query := range queries {
+			if _, err := tx.execDirect(ctx, query); err != nil {
+				tx.discard()
+				return nil, err
 			}
 		}
+		if ctx.Done() != nil {
+			go tx.rollbackOnDone()
+		}
+		return tx, nil
 	}
-	return affected, nil
-}
-
-// Begin starts a transaction.
-func (db *DB) Begin() error {
-	tx, err := db.db.Begin()
+	tx, err := db.db.BeginTx(ctx, opts)
 	if err != nil {
-		return err
+		return nil, err
 	}
-	db.m.Lock()
-	defer db.m.Unlock()
-	db.tx = tx
-	return nil
+	return &Tx{txDB: db.withTx(tx, nil), ctx: ctx}, nil
 }
 
-// Commit commits the transaction.
-// If Begin still not called, or Commit or Rollback already called, Commit returns ErrTxDone.
-func (db *DB) Commit() error {
-	db.m.Lock()
-	defer db.m.Unlock()
-	if db.tx == nil {
-		return ErrTxDone
+// keyPredicate returns the predicate that matches the values of the key
+// fields of objs, and its arguments. The number of placeholders starts from
+// numHolders.
+// For the multiple key fields, it uses the row value constructor if the
+// dialect supports it. Otherwise, it uses the OR-ed AND conditions.
+func (db *DB) keyPredicate(numHolders int, keys []*modelField, objs []interface{}) (string, []interface{}) {
+	var args []interface{}
+	for _, obj := range objs {
+		rv := reflect.Indirect(reflect.ValueOf(obj))
+		for _, f := range keys {
+			args = append(args, rv.FieldByIndex(f.index).Interface())
+		}
 	}
-	err := db.tx.Commit()
-	db.tx = nil
-	return err
-}
-
-// Rollback rollbacks the transaction.
-// If Begin still not called, or Commit or Rollback already called, Rollback returns ErrTxDone.
-func (db *DB) Rollback() error {
-	db.m.Lock()
-	defer db.m.Unlock()
-	if db.tx == nil {
-		return ErrTxDone
+	cols := make([]string, len(keys))
+	for i, f := range keys {
+		cols[i] = db.dialect.Quote(f.column)
+	}
+	rows := make([]string, len(objs))
+	n := numHolders
+	for i := range objs {
+		holders := make([]string, len(keys))
+		for j := range holders {
+			holders[j] = db.dialect.PlaceHolder(n)
+			n++
+		}
+		switch {
+		case len(keys) == 1:
+			rows[i] = holders[0]
+		case db.dialect.SupportsRowValueIn():
+			rows[i] = fmt.Sprintf("(%s)", strings.Join(holders, ", "))
+		default:
+			conds := make([]string, len(holders))
+			for j, holder := range holders {
+				conds[j] = fmt.Sprintf("%s = %s", cols[j], holder)
+			}
+			rows[i] = fmt.Sprintf("(%s)", strings.Join(conds, " AND "))
+		}
+	}
+	switch {
+	case len(keys) == 1:
+		return fmt.Sprintf("%s IN (%s)", cols[0], strings.Join(rows, ", ")), args
+	case db.dialect.SupportsRowValueIn():
+		return fmt.Sprintf("(%s) IN (%s)", strings.Join(cols, ", "), strings.Join(rows, ", ")), args
+	default:
+		return strings.Join(rows, " OR "), args
 	}
-	err := db.tx.Rollback()
-	db.tx = nil
-	return err
 }
 
+// LastInsertId returns the last inserted id.
 func (db *DB) LastInsertId() (int64, error) {
-	stmt, err := db.prepare(db.dialect.LastInsertId())
+	return db.LastInsertIdContext(context.Background())
+}
+
+// LastInsertIdContext is like LastInsertId, but with context.
+func (db *DB) LastInsertIdContext(ctx context.Context) (int64, error) {
+	rows, done, err := db.query(ctx, db.dialect.LastInsertId())
 	if err != nil {
 		return 0, err
 	}
-	defer stmt.Close()
+	defer done()
+	defer rows.Close()
 	var id int64
-	return id, stmt.QueryRow().Scan(&id)
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return 0, err
+		}
+		return 0, sql.ErrNoRows
+	}
+	return id, rows.Scan(&id)
 }
 
 // Raw returns a value that is wrapped with Raw.
@@ -535,7 +1137,11 @@ func (db *DB) Raw(v interface{}) Raw {
 }
 
 // Close closes the database.
+// All statements in the statement cache will be closed.
 func (db *DB) Close() error {
+	if db.stmts != nil {
+		db.stmts.purge()
+	}
 	return db.db.Close()
 }
 
@@ -576,6 +1182,55 @@ func (db *DB) SetLogFormat(format string) error {
 	return db.logger.SetFormat(format)
 }
 
+// SetPrepare sets whether the queries are run through explicit prepared
+// statements. By default, it is true.
+// If false, the queries will be run by Query/Exec of the database/sql
+// directly. It is useful for the connection poolers that don't support
+// prepared statements (e.g. PgBouncer in transaction mode), or to reduce
+// round trips of one-shot queries.
+// It can be overridden for each call by WithPrepare.
+func (db *DB) SetPrepare(prepare bool) {
+	db.noPrepare = !prepare
+}
+
+// WithPrepare returns a copy of ctx that overrides the setting of SetPrepare
+// for the queries that are run with it.
+func WithPrepare(ctx context.Context, prepare bool) context.Context {
+	return context.WithValue(ctx, prepareKey{}, prepare)
+}
+
+// prepareKey is the context key for WithPrepare.
+type prepareKey struct{}
+
+// SetStmtCacheSize enables the cache of prepared statements that keyed by
+// the generated query, and sets the maximum number of statements in it.
+// The least recently used statement will be closed when the cache is full.
+// If n <= 0, it disables the cache and closes all statements in it.
+// By default, the cache is disabled, and every query is prepared and closed
+// for each call.
+func (db *DB) SetStmtCacheSize(n int) {
+	switch {
+	case n <= 0:
+		if db.stmts != nil {
+			db.stmts.purge()
+		}
+		db.stmts = nil
+	case db.stmts == nil:
+		db.stmts = newStmtCache(n)
+	default:
+		db.stmts.resize(n)
+	}
+}
+
+// StmtCacheStats returns statistics of the statement cache.
+// If the cache is disabled, it returns zero value.
+func (db *DB) StmtCacheStats() StmtCacheStats {
+	if db.stmts == nil {
+		return StmtCacheStats{}
+	}
+	return db.stmts.stats()
+}
+
 // selectToSlice returns a slice value fetched from rows.
 func (db *DB) selectToSlice(rows *sql.Rows, t reflect.Type) (reflect.Value, error) {
 	columns, err := rows.Columns()
@@ -587,14 +1242,11 @@ func (db *DB) selectToSlice(rows *sql.Rows, t reflect.Type) (reflect.Value, erro
 	for ; t.Kind() == reflect.Ptr; ptrN++ {
 		t = t.Elem()
 	}
-	fieldIndexes := make([][]int, len(columns))
-	for i, column := range columns {
-		index := db.fieldIndexByName(t, column, nil)
-		if len(index) < 1 {
-			return reflect.Value{}, fmt.Errorf("`%v` field isn't defined in %v or embedded struct", stringutil.ToUpperCamelCase(column), t)
-		}
-		fieldIndexes[i] = index
+	fieldIndexes, err := db.fieldIndexesByColumns(t, columns)
+	if err != nil {
+		return reflect.Value{}, err
 	}
+	m := db.modelOf(t)
 	dest := make([]interface{}, len(columns))
 	var result []reflect.Value
 	for rows.Next() {
@@ -606,6 +1258,7 @@ func (db *DB) selectToSlice(rows *sql.Rows, t reflect.Type) (reflect.Value, erro
 		if err := rows.Scan(dest...); err != nil {
 			return reflect.Value{}, err
 		}
+		db.takeSnapshot(m, v)
 		result = append(result, v)
 	}
 	if err := rows.Err(); err != nil {
@@ -624,6 +1277,69 @@ func (db *DB) selectToSlice(rows *sql.Rows, t reflect.Type) (reflect.Value, erro
 	return slice, nil
 }
 
+// selectToStruct returns a struct value fetched from the first row of rows.
+// If rows is empty, it returns ErrNoRows.
+func (db *DB) selectToStruct(rows *sql.Rows, t reflect.Type) (reflect.Value, error) {
+	columns, err := rows.Columns()
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	fieldIndexes, err := db.fieldIndexesByColumns(t, columns)
+	if err != nil {
+		return reflect.Value{}, err
+	}
+	if !rows.Next() {
+		if err := rows.Err(); err != nil {
+			return reflect.Value{}, err
+		}
+		return reflect.Value{}, ErrNoRows
+	}
+	v := reflect.New(t).Elem()
+	dest := make([]interface{}, len(columns))
+	for i, index := range fieldIndexes {
+		dest[i] = v.FieldByIndex(index).Addr().Interface()
+	}
+	if err := rows.Scan(dest...); err != nil {
+		return reflect.Value{}, err
+	}
+	db.takeSnapshot(db.modelOf(t), v)
+	return v, nil
+}
+
+// limitOne returns args that "LIMIT 1" is added to.
+// If a Condition in args already has "LIMIT" clause, args will be returned as is.
+// Given Conditions won't be modified.
+func (db *DB) limitOne(args []interface{}) []interface{} {
+	last := -1
+	for i, arg := range args {
+		if c, ok := arg.(*Condition); ok {
+			for _, p := range c.parts {
+				if p.clause == Limit {
+					return args
+				}
+			}
+			last = i
+		}
+	}
+	newArgs := append([]interface{}(nil), args...)
+	if last < 0 {
+		return append(newArgs, newCondition(db).Limit(1))
+	}
+	c := newArgs[last].(*Condition)
+	newArgs[last] = &Condition{
+		db:        c.db,
+		parts:     append(append(parts(nil), c.parts...), part{clause: Limit, expr: 1, priority: 500}),
+		tableName: c.tableName,
+	}
+	return newArgs
+}
+
+// isScalarStruct returns whether the struct type t is a single column value
+// such as time.Time and sql.NullString, rather than a row of the table.
+func isScalarStruct(t reflect.Type) bool {
+	return t == timeType || reflect.PtrTo(t).Implements(scannerType)
+}
+
 // selectToValue returns a single value fetched from rows.
 func (db *DB) selectToValue(rows *sql.Rows, t reflect.Type) (reflect.Value, error) {
 	ptrN := 0
@@ -642,20 +1358,65 @@ func (db *DB) selectToValue(rows *sql.Rows, t reflect.Type) (reflect.Value, erro
 	return dest, nil
 }
 
-// fieldIndexByName returns the nested field corresponding to the index sequence.
-func (db *DB) fieldIndexByName(t reflect.Type, name string, index []int) []int {
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if candidate := db.columnFromTag(field); candidate == name {
-			return append(index, i)
+// fieldIndexesByColumns returns the nested field indexes of t corresponding to the columns.
+func (db *DB) fieldIndexesByColumns(t reflect.Type, columns []string) ([][]int, error) {
+	m := db.modelOf(t)
+	fieldIndexes := make([][]int, len(columns))
+	for i, column := range columns {
+		index := m.indexes[column]
+		if len(index) < 1 {
+			return nil, fmt.Errorf("`%v` field isn't defined in %v or embedded struct", stringutil.ToUpperCamelCase(column), t)
+		}
+		fieldIndexes[i] = index
+	}
+	return fieldIndexes, nil
+}
+
+// fromTable returns the table name and the model of the From in args.
+// If From isn't given, it returns empty string. The model will be nil if
+// From isn't created by DB.From.
+func (db *DB) fromTable(name string, args []interface{}) (tableName string, m *model, err error) {
+	for _, arg := range args {
+		if f, ok := arg.(*From); ok {
+			if tableName != "" {
+				return "", nil, fmt.Errorf("%s: From statement specified more than once", name)
+			}
+			tableName, m = f.TableName, f.model
+		}
+	}
+	return tableName, m, nil
+}
+
+// selectQuery returns the "SELECT" query and its arguments built from args.
+// m is the model of the table, and it is used to exclude the soft-deleted
+// records. It can be nil.
+func (db *DB) selectQuery(tableName string, m *model, args []interface{}) (query string, values []interface{}, err error) {
+	col, from, conditions, err := db.classify(tableName, args)
+	if err != nil {
+		return "", nil, err
+	}
+	if !isUnscoped(args) {
+		if err := checkSoftDelete("Select", m); err != nil {
+			return "", nil, err
 		}
-		if field.Anonymous {
-			if idx := db.fieldIndexByName(field.Type, name, append(index, i)); len(idx) > 0 {
-				return append(index, idx...)
+		conditions = db.scoped(tableName, m, conditions)
+		for _, c := range conditions {
+			for _, p := range c.parts {
+				if jc, ok := p.expr.(*JoinCondition); ok {
+					if err := checkSoftDelete("Select", jc.model); err != nil {
+						return "", nil, err
+					}
+				}
 			}
 		}
 	}
-	return nil
+	queries := []string{`SELECT`, col, `FROM`, db.dialect.Quote(from)}
+	for _, cond := range conditions {
+		q, a := cond.build(0, false)
+		queries = append(queries, q...)
+		values = append(values, a...)
+	}
+	return strings.Join(queries, " "), values, nil
 }
 
 func (db *DB) classify(tableName string, args []interface{}) (column, from string, conditions []*Condition, err error) {
@@ -690,7 +1451,7 @@ func (db *DB) classify(tableName string, args []interface{}) (column, from strin
 			conditions = append(conditions, t)
 		case string, []string:
 			return "", "", nil, fmt.Errorf("argument of %T type must be before the *Condition arguments", t)
-		case *From:
+		case *From, *Unscoped:
 			// ignore.
 		case *Function:
 			return "", "", nil, fmt.Errorf("%s function must be specified to the first argument", t.Name)
@@ -725,58 +1486,71 @@ func (db *DB) columns(tableName string, columns []interface{}) string {
 	return strings.Join(names, ", ")
 }
 
-func (db *DB) collectTableFields(t reflect.Type) (fields []string, err error) {
-	for i := 0; i < t.NumField(); i++ {
-		field := t.Field(i)
-		if IsUnexportedField(field) {
-			continue
+func (db *DB) collectTableFields(m *model) (fields []string, err error) {
+	for _, f := range m.fields {
+		field, err := db.columnDefinition("CreateTable", m, f, true)
+		if err != nil {
+			return nil, err
 		}
-		if db.hasSkipTag(&field) {
-			continue
+		fields = append(fields, field)
+	}
+	if len(m.pks) > 1 {
+		cols := make([]string, len(m.pks))
+		for i, f := range m.pks {
+			cols[i] = db.dialect.Quote(f.column)
 		}
-		if field.Anonymous {
-			fs, err := db.collectTableFields(field.Type)
-			if err != nil {
-				return nil, err
+		fields = append(fields, fmt.Sprintf("PRIMARY KEY (%s)", strings.Join(cols, ", ")))
+	}
+	return fields, nil
+}
+
+// columnDefinition returns the column definition of f for "CREATE TABLE".
+// If unique is false, "UNIQUE" will be omitted even if f has "unique" tag.
+func (db *DB) columnDefinition(name string, m *model, f *modelField, unique bool) (string, error) {
+	var options []string
+	for _, tag := range f.tags {
+		switch tag {
+		case "pk":
+			if len(m.pks) > 1 {
+				// table constraint will be added after the columns.
+				continue
 			}
-			fields = append(fields, fs...)
-			continue
-		}
-		var options []string
-		autoIncrement := false
-		for _, tag := range db.tagsFromField(&field) {
-			switch tag {
-			case "pk":
-				options = append(options, "PRIMARY KEY")
-				if db.isAutoIncrementable(&field) {
-					options = append(options, db.dialect.AutoIncrement())
-					autoIncrement = true
-				}
-			case "unique":
+			options = append(options, "PRIMARY KEY")
+			if f.autoIncrement {
+				options = append(options, db.dialect.AutoIncrement())
+			}
+		case "unique":
+			if unique {
 				options = append(options, "UNIQUE")
-			default:
-				return nil, fmt.Errorf(`CreateTable: unsupported field tag: "%v"`, tag)
 			}
+		case "version":
+			if !db.isAutoIncrementable(&f.field) {
+				return "", fmt.Errorf(`%s: "version" tag must be specified to integer field, got %v`, name, f.field.Type)
+			}
+		case "softdelete":
+			if !isSoftDeletable(f.field.Type) {
+				return "", fmt.Errorf(`%s: "softdelete" tag must be specified to *time.Time field, got %v`, name, f.field.Type)
+			}
+		default:
+			return "", fmt.Errorf(`%s: unsupported field tag: "%v"`, name, tag)
 		}
-		size, err := db.sizeFromTag(&field)
-		if err != nil {
-			return nil, err
-		}
-		typName, allowNull := db.dialect.SQLType(reflect.Zero(field.Type).Interface(), autoIncrement, size)
-		if !allowNull {
-			options = append(options, "NOT NULL")
-		}
-		line := append([]string{db.dialect.Quote(db.columnFromTag(field)), typName}, options...)
-		def, err := db.defaultFromTag(&field)
-		if err != nil {
-			return nil, err
-		}
-		if def != "" {
-			line = append(line, def)
-		}
-		fields = append(fields, strings.Join(line, " "))
 	}
-	return fields, nil
+	if f.sizeErr != nil {
+		return "", f.sizeErr
+	}
+	typName, allowNull := db.dialect.SQLType(reflect.Zero(f.field.Type).Interface(), f.autoIncrement, f.size)
+	if !allowNull {
+		options = append(options, "NOT NULL")
+	}
+	line := append([]string{db.dialect.Quote(f.column), typName}, options...)
+	def, err := db.defaultFromTag(f)
+	if err != nil {
+		return "", err
+	}
+	if def != "" {
+		line = append(line, def)
+	}
+	return strings.Join(line, " "), nil
 }
 
 // tagsFromField returns a slice of option strings.
@@ -800,16 +1574,6 @@ func (db *DB) hasSkipTag(field *reflect.StructField) bool {
 	return false
 }
 
-// hasPKTag returns whether the struct field has the "pk" tag.
-func (db *DB) hasPKTag(field *reflect.StructField) bool {
-	for _, tag := range db.tagsFromField(field) {
-		if tag == "pk" {
-			return true
-		}
-	}
-	return false
-}
-
 // isAutoIncrementable returns whether the struct field is integer.
 func (db *DB) isAutoIncrementable(field *reflect.StructField) bool {
 	switch field.Type.Kind() {
@@ -820,47 +1584,6 @@ func (db *DB) isAutoIncrementable(field *reflect.StructField) bool {
 	return false
 }
 
-// collectFieldIndexes returns the indexes of field which doesn't have skip tag and pk tag.
-func (db *DB) collectFieldIndexes(typ reflect.Type, index []int) (indexes [][]int) {
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		if IsUnexportedField(field) {
-			continue
-		}
-		if !(db.hasSkipTag(&field) || (db.hasPKTag(&field) && db.isAutoIncrementable(&field))) {
-			tmp := make([]int, len(index)+1)
-			copy(tmp, index)
-			tmp[len(tmp)-1] = i
-			if field.Anonymous {
-				indexes = append(indexes, db.collectFieldIndexes(field.Type, tmp)...)
-			} else {
-				indexes = append(indexes, tmp)
-			}
-		}
-	}
-	return indexes
-}
-
-// findPKIndex returns the nested field corresponding to the index sequence of field of primary key.
-func (db *DB) findPKIndex(typ reflect.Type, index []int) []int {
-	for i := 0; i < typ.NumField(); i++ {
-		field := typ.Field(i)
-		if IsUnexportedField(field) {
-			continue
-		}
-		if field.Anonymous {
-			if idx := db.findPKIndex(field.Type, append(index, i)); idx != nil {
-				return append(index, idx...)
-			}
-			continue
-		}
-		if db.hasPKTag(&field) {
-			return append(index, i)
-		}
-	}
-	return nil
-}
-
 // sizeFromTag returns a size from tag.
 // If "size" tag specified to struct field, it will converted to uint64 and returns it.
 // If it doesn't specify, it returns 0.
@@ -872,13 +1595,6 @@ func (db *DB) sizeFromTag(field *reflect.StructField) (size uint64, err error) {
 	return size, err
 }
 
-func (db *DB) tableName(t reflect.Type) string {
-	if table, ok := reflect.New(t).Interface().(TableNamer); ok {
-		return table.TableName()
-	}
-	return stringutil.ToSnakeCase(t.Name())
-}
-
 // columnFromTag returns the column name.
 // If "column" tag specified to struct field, returns it.
 // Otherwise, it returns snake-cased field name as column name.
@@ -893,12 +1609,12 @@ func (db *DB) columnFromTag(field reflect.StructField) string {
 // defaultFromTag returns a "DEFAULT ..." keyword.
 // If "default" tag specified to struct field, it use as the default value.
 // If it doesn't specify, it returns empty string.
-func (db *DB) defaultFromTag(field *reflect.StructField) (string, error) {
-	def := field.Tag.Get(dbDefaultTag)
+func (db *DB) defaultFromTag(f *modelField) (string, error) {
+	def := f.def
 	if def == "" {
 		return "", nil
 	}
-	switch field.Type.Kind() {
+	switch f.field.Type.Kind() {
 	case reflect.Bool:
 		b, err := strconv.ParseBool(def)
 		if err != nil {
@@ -909,11 +1625,11 @@ func (db *DB) defaultFromTag(field *reflect.StructField) (string, error) {
 	return fmt.Sprintf("DEFAULT %v", def), nil
 }
 
-func (db *DB) tableObjs(name string, obj interface{}) (objs []interface{}, rtype reflect.Type, tableName string, err error) {
+func (db *DB) tableObjs(name string, obj interface{}) (objs []interface{}, m *model, err error) {
 	switch v := reflect.Indirect(reflect.ValueOf(obj)); v.Kind() {
 	case reflect.Slice:
 		if v.Len() < 1 {
-			return objs, nil, "", nil
+			return objs, nil, nil
 		}
 		for i := 0; i < v.Len(); i++ {
 			sv := v.Index(i)
@@ -939,34 +1655,163 @@ func (db *DB) tableObjs(name string, obj interface{}) (objs []interface{}, rtype
 		}
 		objs = append(objs, v.Addr().Interface())
 	}
-	_, rtype, tableName, err = db.tableValueOf(name, objs[0])
-	return objs, rtype, tableName, err
+	_, m, err = db.tableValueOf(name, objs[0])
+	return objs, m, err
 Error:
-	return nil, nil, "", fmt.Errorf("%s: argument must be pointer to struct or slice of struct, got %T", name, obj)
+	return nil, nil, fmt.Errorf("%s: argument must be pointer to struct or slice of struct, got %T", name, obj)
 }
 
-func (db *DB) tableValueOf(name string, table interface{}) (rv reflect.Value, rt reflect.Type, tableName string, err error) {
+func (db *DB) tableValueOf(name string, table interface{}) (rv reflect.Value, m *model, err error) {
 	rv = reflect.Indirect(reflect.ValueOf(table))
-	rt = rv.Type()
+	rt := rv.Type()
 	if rt.Kind() != reflect.Struct {
-		return rv, rt, "", fmt.Errorf("%s: a table must be struct type, got %v", name, rt)
+		return rv, nil, fmt.Errorf("%s: a table must be struct type, got %v", name, rt)
+	}
+	m = db.modelOf(rt)
+	if m.tableName == "" {
+		return rv, nil, fmt.Errorf("%s: a table isn't named", name)
+	}
+	return rv, m, nil
+}
+
+// withTx returns a copy of the DB that is bound to the tx or conn.
+func (db *DB) withTx(tx *sql.Tx, conn *sql.Conn) *DB {
+	return &DB{
+		db:        db.db,
+		dialect:   db.dialect,
+		tx:        tx,
+		conn:      conn,
+		logger:    db.logger,
+		txRetries: db.txRetries,
+		stmts:     db.stmts,
+		noPrepare: db.noPrepare,
+		returning: db.returning,
+	}
+}
+
+// inTx returns whether the DB is bound to a transaction.
+func (db *DB) inTx() bool {
+	return db.tx != nil || db.conn != nil
+}
+
+// queryer returns the object to run a query on.
+// It is the transaction if the DB is bound to it, otherwise the *sql.DB.
+func (db *DB) queryer() queryer {
+	switch {
+	case db.tx != nil:
+		return db.tx
+	case db.conn != nil:
+		return db.conn
+	}
+	return db.db
+}
+
+// exec executes the query.
+// If the prepared mode is disabled by SetPrepare or WithPrepare, the query
+// will be executed without an explicit prepared statement.
+func (db *DB) exec(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	if !db.usePrepare(ctx) {
+		return db.execDirect(ctx, query, args...)
+	}
+	stmt, err := db.prepare(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer stmt.Close()
+	return stmt.ExecContext(ctx, args...)
+}
+
+// execDirect executes the query without an explicit prepared statement.
+func (db *DB) execDirect(ctx context.Context, query string, args ...interface{}) (sql.Result, error) {
+	defer db.logger.Print(now(), query, args...)
+	return db.queryer().ExecContext(ctx, query, args...)
+}
+
+// query runs the query that returns rows.
+// If the prepared mode is disabled by SetPrepare or WithPrepare, the query
+// will be run without an explicit prepared statement.
+// done must be called after the rows are closed.
+func (db *DB) query(ctx context.Context, query string, args ...interface{}) (rows *sql.Rows, done func() error, err error) {
+	if !db.usePrepare(ctx) {
+		defer db.logger.Print(now(), query, args...)
+		rows, err := db.queryer().QueryContext(ctx, query, args...)
+		if err != nil {
+			return nil, nil, err
+		}
+		return rows, func() error { return nil }, nil
+	}
+	stmt, err := db.prepare(ctx, query, args...)
+	if err != nil {
+		return nil, nil, err
+	}
+	if rows, err = stmt.QueryContext(ctx, args...); err != nil {
+		stmt.Close()
+		return nil, nil, err
 	}
-	tableName = db.tableName(rt)
-	if tableName == "" {
-		return rv, rt, "", fmt.Errorf("%s: a table isn't named", name)
+	return rows, stmt.Close, nil
+}
+
+// usePrepare returns whether the queries with ctx are run through prepared statements.
+func (db *DB) usePrepare(ctx context.Context) bool {
+	if prepare, ok := ctx.Value(prepareKey{}).(bool); ok {
+		return prepare
 	}
-	return rv, rt, tableName, nil
+	return !db.noPrepare
 }
 
-func (db *DB) prepare(query string, args ...interface{}) (*sql.Stmt, error) {
+// prepare returns a prepared statement of the query.
+// If the statement cache is enabled, the statement will be taken from it, or
+// prepared on the *sql.DB and added to it.
+// In a transaction, the cached statement will be used through Stmt of
+// *sql.Tx, and the statement that isn't cached is prepared in the
+// transaction without caching. The returned statement must be closed after
+// use.
+func (db *DB) prepare(ctx context.Context, query string, args ...interface{}) (*stmt, error) {
 	defer db.logger.Print(now(), query, args...)
-	db.m.Lock()
-	defer db.m.Unlock()
+	if db.stmts == nil || db.conn != nil {
+		return db.prepareUncached(ctx, query)
+	}
+	entry := db.stmts.get(query)
 	if db.tx == nil {
-		return db.db.Prepare(query)
-	} else {
-		return db.tx.Prepare(query)
+		if entry == nil {
+			st, err := db.db.PrepareContext(ctx, query)
+			if err != nil {
+				return nil, err
+			}
+			entry = db.stmts.add(query, st)
+		}
+		return &stmt{Stmt: entry.stmt, close: func() error {
+			return db.stmts.release(entry)
+		}}, nil
+	}
+	if entry == nil {
+		return db.prepareUncached(ctx, query)
+	}
+	st := db.tx.StmtContext(ctx, entry.stmt)
+	return &stmt{Stmt: st, close: func() error {
+		err := st.Close()
+		if e := db.stmts.release(entry); err == nil {
+			err = e
+		}
+		return err
+	}}, nil
+}
+
+// prepareUncached returns a prepared statement of the query that is
+// prepared on the queryer without the statement cache.
+func (db *DB) prepareUncached(ctx context.Context, query string) (*stmt, error) {
+	st, err := db.queryer().PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
 	}
+	return &stmt{Stmt: st, close: st.Close}, nil
+}
+
+// queryer is the interface that *sql.DB, *sql.Tx and *sql.Conn implement.
+type queryer interface {
+	ExecContext(ctx context.Context, query string, args ...interface{}) (sql.Result, error)
+	PrepareContext(ctx context.Context, query string) (*sql.Stmt, error)
+	QueryContext(ctx context.Context, query string, args ...interface{}) (*sql.Rows, error)
 }
 
 type selectFunc func(*sql.Rows, reflect.Type) (reflect.Value, error)
@@ -1023,6 +1868,8 @@ type Raw *interface{}
 // From represents a "FROM" statement.
 type From struct {
 	TableName string
+
+	model *model // model of the table. nil if it isn't created by DB.From.
 }
 
 // Distinct represents a "DISTINCT" statement.
@@ -1223,7 +2070,7 @@ func (c *Condition) appendQueryByCondOrExpr(name string, order int, clause Claus
 		if v.Kind() != reflect.Struct {
 			panic(fmt.Errorf("%s: first argument must be string or struct, got %T", name, t))
 		}
-		args = append([]interface{}{c.db.tableName(v.Type())}, args...)
+		args = append([]interface{}{c.db.modelOf(v.Type()).tableName}, args...)
 	}
 	switch len(args) {
 	case 1: // Where(Where("id", "=", 1))
@@ -1275,7 +2122,7 @@ func (c *Condition) orderBy(table, col, order interface{}) orderBy {
 		for rt.Kind() == reflect.Ptr {
 			rt = rt.Elem()
 		}
-		o.column.table = c.db.tableName(rt)
+		o.column.table = c.db.modelOf(rt).tableName
 	}
 	return o
 }
@@ -1330,6 +2177,9 @@ func (c *Condition) build(numHolders int, inner bool) (queries []string, args []
 			queries = append(queries,
 				c.db.dialect.Quote(e.tableName), "ON",
 				ColumnName(c.db.dialect, leftTableName, e.left), e.op, ColumnName(c.db.dialect, e.tableName, e.right))
+			if e.scoped && e.model != nil && e.model.softDelete != nil {
+				queries = append(queries, "AND", ColumnName(c.db.dialect, e.tableName, e.model.softDelete.column), IsNull.String())
+			}
 		case nil:
 			// ignore.
 		default:
@@ -1350,6 +2200,8 @@ type JoinCondition struct {
 	left          string // A left column name of operator.
 	right         string // A right column name of operator.
 	clause        Clause // A type of join clause ("JOIN" or "LEFT JOIN")
+	model         *model // A model of the table of 'to join'.
+	scoped        bool   // Whether the soft-deleted records of the table of 'to join' are excluded.
 }
 
 // Join adds table name to the JoinCondition of "JOIN".
@@ -1377,7 +2229,7 @@ func (jc *JoinCondition) On(larg interface{}, args ...string) *Condition {
 		if rv.Kind() != reflect.Struct {
 			panic(fmt.Errorf("On: first argument must be string or struct, got %v", rv.Type()))
 		}
-		jc.leftTableName = jc.db.tableName(rv.Type())
+		jc.leftTableName = jc.db.modelOf(rv.Type()).tableName
 		lcolumn, args = args[0], args[1:]
 	}
 	switch len(args) {
@@ -1402,7 +2254,8 @@ func (jc *JoinCondition) join(joinClause Clause, table interface{}) *JoinConditi
 	if t.Kind() != reflect.Struct {
 		panic(fmt.Errorf("%v: a table must be struct type, got %v", joinClause, t))
 	}
-	jc.tableName = jc.db.tableName(t)
+	jc.model = jc.db.modelOf(t)
+	jc.tableName = jc.model.tableName
 	jc.clause = joinClause
 	return jc
 }
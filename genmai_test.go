@@ -2,8 +2,10 @@ package genmai
 
 import (
 	"bytes"
+	"context"
 	"fmt"
 	"io/ioutil"
+	"math/big"
 	"os"
 	"path/filepath"
 	"reflect"
@@ -337,16 +339,17 @@ func Test_Select(t *testing.T) {
 	func() {
 		db := newTestDB(t)
 		defer db.Close()
-		if err := db.Begin(); err != nil {
+		tx, err := db.Begin()
+		if err != nil {
 			t.Fatal(err)
 		}
 		defer func() {
-			if err := db.Rollback(); err != nil {
+			if err := tx.Rollback(); err != nil {
 				t.Error(err)
 			}
 		}()
 		var actual []testModel
-		if err := db.Select(&actual); err != nil {
+		if err := tx.Select(&actual); err != nil {
 			t.Fatal(err)
 		}
 		expected := []testModel{
@@ -1053,6 +1056,46 @@ func TestDB_Select_embeddedStruct(t *testing.T) {
 	}
 }
 
+func TestDB_Select_struct(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	cond := db.Where("name", "=", "other").OrderBy("id", DESC)
+	for _, v := range []struct {
+		args   []interface{}
+		expect testModel
+	}{
+		{nil, testModel{1, "test1", "addr1"}},
+		{[]interface{}{cond}, testModel{5, "other", "addr5"}},
+		{[]interface{}{[]string{"id", "name"}, db.Where("id", "=", 3)}, testModel{3, "test3", ""}},
+		{[]interface{}{db.OrderBy("id", ASC).Limit(2).Offset(2)}, testModel{3, "test3", "addr3"}},
+	} {
+		var actual testModel
+		if err := db.Select(&actual, v.args...); err != nil {
+			t.Errorf("DB.Select(%#v) => %#v; want nil", v.args, err)
+			continue
+		}
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf("DB.Select(%#v) => %#v; want %#v", v.args, actual, expect)
+		}
+	}
+
+	// given Condition must not be modified.
+	var results []testModel
+	if err := db.Select(&results, cond); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := len(results), 2; actual != expect {
+		t.Errorf("len(results) => %v; want %v", actual, expect)
+	}
+
+	var actual testModel
+	err := db.Select(&actual, db.Where("id", "=", 100))
+	if expect := ErrNoRows; err != expect {
+		t.Errorf("DB.Select(...) => %#v; want %#v", err, expect)
+	}
+}
+
 func TestDB_CreateTable(t *testing.T) {
 	func() {
 		type TestTable struct {
@@ -1748,11 +1791,12 @@ func TestDB_Update_withTransaction(t *testing.T) {
 			t.Fatal(fmt.Errorf("%v: %s", err, query))
 		}
 	}
-	if err := db1.Begin(); err != nil {
+	tx, err := db1.Begin()
+	if err != nil {
 		t.Fatal(err)
 	}
 	obj := &TestTable{Id: 1, Name: "updated"}
-	affected, err := db1.Update(obj)
+	affected, err := tx.Update(obj)
 	if err != nil {
 		t.Fatal(err)
 	}
@@ -1771,7 +1815,7 @@ func TestDB_Update_withTransaction(t *testing.T) {
 	if !reflect.DeepEqual(actual, expected) {
 		t.Errorf("Expect %#v, but %#v", expected, actual)
 	}
-	if err := db1.Commit(); err != nil {
+	if err := tx.Commit(); err != nil {
 		t.Fatal(err)
 	}
 	if err := db2.QueryRow(`SELECT * FROM test_table`).Scan(&id, &name); err != nil {
@@ -1871,6 +1915,459 @@ func TestDB_Update_hook(t *testing.T) {
 	}()
 }
 
+func TestDB_UpdateColumns(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+		Addr string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text", "addr text"),
+		`INSERT INTO test_table (id, name, addr) VALUES (1, 'test1', 'addr1')`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	n, err := db.UpdateColumns(&TestTable{Id: 1, Name: "updated", Addr: "ignored"}, "name")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.UpdateColumns(...) => %v; want %v", actual, expect)
+	}
+	var actual TestTable
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if expect := (TestTable{1, "updated", "addr1"}); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(...) => %#v; want %#v", actual, expect)
+	}
+
+	for _, columns := range [][]string{{"unknown"}, {"id"}, nil} {
+		if _, err := db.UpdateColumns(&TestTable{Id: 1}, columns...); err == nil {
+			t.Errorf("DB.UpdateColumns(obj, %#v) => nil; want error", columns)
+		}
+	}
+}
+
+func TestDB_Update_dirtyTracking(t *testing.T) {
+	type TestTable struct {
+		DirtyTracker
+		Id   int64 `db:"pk"`
+		Name string
+		Addr string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text", "addr text"),
+		`INSERT INTO test_table (id, name, addr) VALUES (1, 'test1', 'addr1')`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	var obj TestTable
+	if err := db.Select(&obj); err != nil {
+		t.Fatal(err)
+	}
+	// nothing changed.
+	n, err := db.Update(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(0); actual != expect {
+		t.Errorf("DB.Update(unchanged) => %v; want %v", actual, expect)
+	}
+
+	// concurrent change to the other column must not be clobbered.
+	if _, err := db.db.Exec(`UPDATE test_table SET addr = 'concurrent'`); err != nil {
+		t.Fatal(err)
+	}
+	obj.Name = "updated"
+	if n, err = db.Update(&obj); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Update(changed) => %v; want %v", actual, expect)
+	}
+	var actual []TestTable
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != 1 || actual[0].Name != "updated" || actual[0].Addr != "concurrent" {
+		t.Errorf("DB.Select(...) => %#v; want name %q and addr %q", actual, "updated", "concurrent")
+	}
+
+	// snapshot is taken by Update, and it can be discarded.
+	if n, err = db.Update(&obj); err != nil || n != 0 {
+		t.Errorf("DB.Update(updated) => (%v, %v); want (0, nil)", n, err)
+	}
+	obj.ResetSnapshot()
+	if _, err = db.Update(&obj); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual[0].Addr != "addr1" {
+		t.Errorf("DB.Update(reset) => addr %q; want %q", actual[0].Addr, "addr1")
+	}
+
+	// UpdateColumns refreshes the snapshot of only the written columns.
+	obj.Name = "columns"
+	obj.Addr = "pending"
+	if _, err = db.UpdateColumns(&obj, "name"); err != nil {
+		t.Fatal(err)
+	}
+	if n, err = db.Update(&obj); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Update(after UpdateColumns) => %v; want %v", actual, expect)
+	}
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual[0].Name != "columns" || actual[0].Addr != "pending" {
+		t.Errorf("DB.Select(...) => %#v; want name %q and addr %q", actual, "columns", "pending")
+	}
+}
+
+func TestDB_Update_dirtyTrackingInPlace(t *testing.T) {
+	type TestTable struct {
+		DirtyTracker
+		Id    int64 `db:"pk"`
+		Note  *string
+		Price Rat
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "note text", "price decimal(65, 30) not null"),
+		`INSERT INTO test_table (id, note, price) VALUES (1, 'old', 1.5)`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	var obj TestTable
+	if err := db.Select(&obj); err != nil {
+		t.Fatal(err)
+	}
+	*obj.Note = "new"
+	n, err := db.Update(&obj)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Update(pointer changed in place) => %v; want %v", actual, expect)
+	}
+	obj.Price.SetInt64(2)
+	if n, err = db.Update(&obj); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Update(Rat changed in place) => %v; want %v", actual, expect)
+	}
+	var actual TestTable
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual.Note == nil || *actual.Note != "new" || actual.Price.Cmp(big.NewRat(2, 1)) != 0 {
+		t.Errorf("DB.Select(...) => note %v, price %v; want note %q, price 2", actual.Note, actual.Price, "new")
+	}
+}
+
+func TestDB_Update_version(t *testing.T) {
+	type TestTable struct {
+		Id      int64 `db:"pk"`
+		Name    string
+		Version int64 `db:"version"`
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS test_table`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTable(&TestTable{}); err != nil {
+		t.Fatal(err)
+	}
+	obj := &TestTable{Name: "test1", Version: 1}
+	if _, err := db.Insert(obj); err != nil {
+		t.Fatal(err)
+	}
+	other := *obj
+	obj.Name = "updated"
+	if _, err := db.Update(obj); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := obj.Version, int64(2); actual != expect {
+		t.Errorf("DB.Update(obj); obj.Version => %v; want %v", actual, expect)
+	}
+	other.Name = "lost"
+	n, err := db.Update(&other)
+	if expect := ErrStaleObject; err != expect {
+		t.Errorf("DB.Update(stale) => _, %#v; want %#v", err, expect)
+	}
+	if actual, expect := n, int64(0); actual != expect {
+		t.Errorf("DB.Update(stale) => %v; want %v", actual, expect)
+	}
+	if actual, expect := other.Version, int64(1); actual != expect {
+		t.Errorf("DB.Update(stale); obj.Version => %v; want %v", actual, expect)
+	}
+	if _, err := db.UpdateColumns(obj, "name"); err != nil {
+		t.Fatal(err)
+	}
+	var actual TestTable
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if expect := (TestTable{1, "updated", 3}); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(...) => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_Delete_version(t *testing.T) {
+	type TestTable struct {
+		Id      int64 `db:"pk"`
+		Name    string
+		Version int64 `db:"version"`
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS test_table`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTable(&TestTable{}); err != nil {
+		t.Fatal(err)
+	}
+	objs := []TestTable{{Name: "test1", Version: 1}, {Name: "test2", Version: 1}}
+	if _, err := db.Insert(objs); err != nil {
+		t.Fatal(err)
+	}
+	updated := objs[1]
+	if _, err := db.Update(&updated); err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.Delete(objs)
+	if expect := ErrStaleObject; err != expect {
+		t.Errorf("DB.Delete(stale) => _, %#v; want %#v", err, expect)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Delete(stale) => %v; want %v", actual, expect)
+	}
+	if n, err = db.Delete(&updated); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Delete(obj) => %v; want %v", actual, expect)
+	}
+}
+
+func TestDB_Delete_softDelete(t *testing.T) {
+	type SoftUser struct {
+		Id        int64 `db:"pk"`
+		Name      string
+		DeletedAt *time.Time `db:"softdelete"`
+	}
+	type SoftProfile struct {
+		Id        int64 `db:"pk"`
+		Bio       string
+		DeletedAt *time.Time `db:"softdelete"`
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, table := range []interface{}{&SoftUser{}, &SoftProfile{}} {
+		if err := db.CreateTableIfNotExists(table); err != nil {
+			t.Fatal(err)
+		}
+	}
+	users := []SoftUser{{Name: "alice"}, {Name: "bob"}, {Name: "carol"}}
+	if _, err := db.Insert(users); err != nil {
+		t.Fatal(err)
+	}
+	profiles := []SoftProfile{{Bio: "a"}, {Bio: "b"}, {Bio: "c"}}
+	if _, err := db.Insert(profiles); err != nil {
+		t.Fatal(err)
+	}
+	n, err := db.Delete(&users[1])
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Delete(obj) => %v; want %v", actual, expect)
+	}
+	if users[1].DeletedAt == nil {
+		t.Errorf("DB.Delete(obj) => DeletedAt is nil; want timestamp")
+	}
+	if n, err = db.Delete(&users[1]); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(0); actual != expect {
+		t.Errorf("DB.Delete(deleted) => %v; want %v", actual, expect)
+	}
+	names := func(users []SoftUser) (names []string) {
+		for _, u := range users {
+			names = append(names, u.Name)
+		}
+		return names
+	}
+
+	var actual []SoftUser
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := names(actual), []string{"alice", "carol"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(&users) => %#v; want %#v", actual, expect)
+	}
+	actual = nil
+	if err := db.Select(&actual, db.Where("name", "=", "bob").Or("name", "=", "carol")); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := names(actual), []string{"carol"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(&users, where) => %#v; want %#v", actual, expect)
+	}
+	var user SoftUser
+	if err := db.Select(&user, db.Where("name", "=", "bob")); err != ErrNoRows {
+		t.Errorf("DB.Select(&user, deleted) => %#v; want %#v", err, ErrNoRows)
+	}
+	var count int64
+	if err := db.Select(&count, db.Count(), db.From(&SoftUser{})); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := count, int64(2); actual != expect {
+		t.Errorf("DB.Select(&count) => %v; want %v", actual, expect)
+	}
+	if _, err := db.Delete(&profiles[2]); err != nil {
+		t.Fatal(err)
+	}
+	actual = nil
+	if err := db.Select(&actual, db.Join(&SoftProfile{}).On("id")); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := names(actual), []string{"alice"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(&users, join) => %#v; want %#v", actual, expect)
+	}
+	actual = nil
+	if err := db.Select(&actual, db.Unscoped(), db.Join(&SoftProfile{}).On("id")); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := names(actual), []string{"alice", "bob", "carol"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(&users, Unscoped, join) => %#v; want %#v", actual, expect)
+	}
+
+	if n, err = db.HardDelete(&users[1]); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.HardDelete(obj) => %v; want %v", actual, expect)
+	}
+	actual = nil
+	if err := db.Select(&actual, db.Unscoped()); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := names(actual), []string{"alice", "carol"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(&users, Unscoped) => %#v; want %#v", actual, expect)
+	}
+
+	cond := db.Where("name", "=", "carol")
+	for _, expect := range []int64{1, 0} {
+		if n, err = db.DeleteWhere(&SoftUser{}, cond); err != nil {
+			t.Fatal(err)
+		}
+		if actual := n; actual != expect {
+			t.Errorf("DB.DeleteWhere(...) => %v; want %v", actual, expect)
+		}
+	}
+	actual = nil
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := names(actual), []string{"alice"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(&users) after DeleteWhere => %#v; want %#v", actual, expect)
+	}
+	if n, err = db.HardDeleteWhere(&SoftUser{}, cond); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.HardDeleteWhere(...) => %v; want %v", actual, expect)
+	}
+	actual = nil
+	if err := db.Select(&actual, db.Unscoped()); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := names(actual), []string{"alice"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(&users, Unscoped) after HardDeleteWhere => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_Delete_softDeleteNonPointer(t *testing.T) {
+	type TestTable struct {
+		Id        int64 `db:"pk"`
+		Name      string
+		DeletedAt time.Time `db:"softdelete"`
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if err := db.CreateTable(&TestTable{}); err == nil {
+		t.Errorf("DB.CreateTable(time.Time softdelete) => nil; want error")
+	}
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text", "deleted_at timestamp"),
+		`INSERT INTO test_table (id, name) VALUES (1, 'alice')`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	var actual []TestTable
+	if err := db.Select(&actual); err == nil {
+		t.Errorf("DB.Select(time.Time softdelete) => nil; want error")
+	}
+	if _, err := db.Delete(&TestTable{Id: 1}); err == nil {
+		t.Errorf("DB.Delete(time.Time softdelete) => nil; want error")
+	}
+	if _, err := db.DeleteWhere(&TestTable{}, db.Where("id", "=", 1)); err == nil {
+		t.Errorf("DB.DeleteWhere(time.Time softdelete) => nil; want error")
+	}
+	var count int64
+	if err := db.Select(&count, db.Count(), db.From(&TestTable{}), db.Unscoped()); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := count, int64(1); actual != expect {
+		t.Errorf("DB.Select(&count, Unscoped) => %v; want %v", actual, expect)
+	}
+}
+
 func TestDB_Insert(t *testing.T) {
 	type TestTable struct {
 		Id         int64 `db:"pk"`
@@ -1981,7 +2478,7 @@ func TestDB_Insert(t *testing.T) {
 	}()
 
 	// test for multiple.
-	testCaseMultiple := func(objs interface{}) {
+	testCaseMultiple := func(objs interface{}, expectIds []int64) {
 		db, err := testDB()
 		if err != nil {
 			t.Fatal(err)
@@ -2013,7 +2510,7 @@ func TestDB_Insert(t *testing.T) {
 				id = table.Id
 			}
 			actual := id
-			expect := int64(200)
+			expect := expectIds[i]
 			if !reflect.DeepEqual(actual, expect) {
 				t.Errorf(`DB.Insert(%#v); obj.Id => (%[2]T=%#[2]v); want (%[3]T=%#[3]v)`, objs, actual, expect)
 			}
@@ -2042,19 +2539,20 @@ func TestDB_Insert(t *testing.T) {
 	testCaseMultiple([]TestTable{
 		{Id: 200, Name: "test2"},
 		{Id: 200, Name: "test3"},
-	})
+	}, []int64{1, 2})
 	testCaseMultiple([]*TestTable{
 		{Id: 200, Name: "test2"},
 		{Id: 200, Name: "test3"},
-	})
+	}, []int64{1, 2})
+	// IDs cannot be set to the values in interface.
 	testCaseMultiple([]interface{}{
 		TestTable{Id: 200, Name: "test2"},
 		TestTable{Id: 200, Name: "test3"},
-	})
+	}, []int64{200, 200})
 	testCaseMultiple([]interface{}{
 		&TestTable{Id: 200, Name: "test2"},
 		&TestTable{Id: 200, Name: "test3"},
-	})
+	}, []int64{1, 2})
 
 	// test for case that primary key is string.
 	func() {
@@ -2088,6 +2586,114 @@ func TestDB_Insert(t *testing.T) {
 	}()
 }
 
+type testRowByRowDialect struct {
+	*SQLite3Dialect
+}
+
+func (d *testRowByRowDialect) BulkInsertIDStrategy() InsertIDStrategy {
+	return InsertIDRowByRow
+}
+
+func TestDB_Insert_bulkInsertIDStrategy(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	for _, dialect := range []Dialect{
+		&SQLite3Dialect{},
+		&testRowByRowDialect{&SQLite3Dialect{}},
+	} {
+		db, err := New(&SQLite3Dialect{}, ":memory:")
+		if err != nil {
+			t.Fatal(err)
+		}
+		db.dialect = dialect
+		if err := db.CreateTable(&TestTable{}); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := db.Insert([]TestTable{{Name: "test1"}, {Name: "test2"}}); err != nil {
+			t.Fatal(err)
+		}
+		objs := []*TestTable{{Name: "test3"}, {Name: "test4"}, {Name: "test5"}}
+		n, err := db.Insert(objs)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if actual, expect := n, int64(3); actual != expect {
+			t.Errorf("%T: DB.Insert(...) => %v; want %v", dialect, actual, expect)
+		}
+		var actual []int64
+		for _, obj := range objs {
+			actual = append(actual, obj.Id)
+		}
+		if expect := []int64{3, 4, 5}; !reflect.DeepEqual(actual, expect) {
+			t.Errorf("%T: DB.Insert(...); ids => %#v; want %#v", dialect, actual, expect)
+		}
+		db.Close()
+	}
+}
+
+type testMaxParametersDialect struct {
+	*SQLite3Dialect
+}
+
+func (d *testMaxParametersDialect) MaxParameters() int {
+	return 5
+}
+
+func TestDB_Insert_chunk(t *testing.T) {
+	type TestTable struct {
+		Id   int64  `db:"pk"`
+		Name string `db:"unique"`
+		Addr string
+	}
+	db, err := New(&SQLite3Dialect{}, ":memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.dialect = &testMaxParametersDialect{&SQLite3Dialect{}}
+	if err := db.CreateTable(&TestTable{}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	db.SetLogOutput(&buf)
+	db.SetLogFormat("{{.query}}")
+	objs := make([]TestTable, 5)
+	for i := range objs {
+		objs[i].Name = fmt.Sprintf("test%d", i+1)
+	}
+	n, err := db.Insert(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(5); actual != expect {
+		t.Errorf("DB.Insert(...) => %v; want %v", actual, expect)
+	}
+	// 2 rows (4 parameters) per statement.
+	if actual, expect := strings.Count(buf.String(), "INSERT INTO"), 3; actual != expect {
+		t.Errorf("number of INSERT => %v; want %v", actual, expect)
+	}
+	for i, obj := range objs {
+		if actual, expect := obj.Id, int64(i+1); actual != expect {
+			t.Errorf("objs[%d].Id => %v; want %v", i, actual, expect)
+		}
+	}
+
+	// rolled back on error.
+	objs = []TestTable{{Name: "test6"}, {Name: "test7"}, {Name: "test1"}}
+	if _, err := db.Insert(objs); err == nil {
+		t.Errorf("DB.Insert(duplicated) => _, nil; want error")
+	}
+	var count int64
+	if err := db.Select(&count, db.Count(), db.From(&TestTable{})); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := count, int64(5); actual != expect {
+		t.Errorf("count => %v; want %v", actual, expect)
+	}
+}
+
 func TestDB_Insert_withColumnTab(t *testing.T) {
 	db, err := testDB()
 	if err != nil {
@@ -2182,15 +2788,16 @@ func TestDB_LastInsertId_withTransaction(t *testing.T) {
 		if _, err := db.db.Exec(`INSERT INTO test_table (name) VALUES ('naoina')`); err != nil {
 			t.Fatal(err)
 		}
-		if err := db.Begin(); err != nil {
+		tx, err := db.Begin()
+		if err != nil {
 			t.Fatal(err)
 		}
-		id, err := db.LastInsertId()
+		id, err := tx.LastInsertId()
 		if err != nil {
 			t.Error(err)
 			continue
 		}
-		if err := db.Rollback(); err != nil {
+		if err := tx.Rollback(); err != nil {
 			t.Fatal(err)
 		}
 		actual := id
@@ -2862,6 +3469,162 @@ func TestDB_Delete_hook(t *testing.T) {
 	}()
 }
 
+func TestDB_UpdateWhere(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	n, err := db.UpdateWhere(&testModel{}, map[string]interface{}{"addr": "updated", "name": "renamed"}, db.Where("name", "=", "other"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(2); actual != expect {
+		t.Errorf("DB.UpdateWhere(map) => %v; want %v", actual, expect)
+	}
+	n, err = db.UpdateWhere(&testModel{}, &testModel{Addr: "struct"}, db.Where("id").In(1, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(2); actual != expect {
+		t.Errorf("DB.UpdateWhere(struct) => %v; want %v", actual, expect)
+	}
+	var actual []testModel
+	if err := db.Select(&actual, db.Where("id", "<=", 5)); err != nil {
+		t.Fatal(err)
+	}
+	expect := []testModel{
+		{1, "test1", "struct"},
+		{2, "test2", "struct"},
+		{3, "test3", "addr3"},
+		{4, "renamed", "updated"},
+		{5, "renamed", "updated"},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(...) => %#v; want %#v", actual, expect)
+	}
+	if _, err := db.UpdateWhere(&testModel{}, map[string]interface{}{"name": "all"}, nil); err == nil {
+		t.Errorf("DB.UpdateWhere(nil condition) => nil; want error")
+	}
+	if _, err := db.UpdateWhere(&testModel{}, map[string]interface{}{"name": "all"}, db.Limit(1)); err == nil {
+		t.Errorf("DB.UpdateWhere(without WHERE) => nil; want error")
+	}
+	cond := db.Where("id", "=", 1)
+	if _, err := db.UpdateWhere(&testModel{}, map[string]interface{}{"addr": "cond"}, cond); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := cond.tableName, ""; actual != expect {
+		t.Errorf("DB.UpdateWhere(...); cond.tableName => %#v; want %#v", actual, expect)
+	}
+	if _, err := db.UpdateWhere(&testModel{}, "name", db.Where("id", "=", 1)); err == nil {
+		t.Errorf("DB.UpdateWhere(string) => nil; want error")
+	}
+}
+
+func TestDB_DeleteWhere(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	n, err := db.DeleteWhere(&testModel{}, db.Where("name", "=", "dup").Or("id", "=", 1))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(3); actual != expect {
+		t.Errorf("DB.DeleteWhere(...) => %v; want %v", actual, expect)
+	}
+	var count int64
+	if err := db.Select(&count, db.Count(), db.From(&testModel{})); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := count, int64(6); actual != expect {
+		t.Errorf("count => %v; want %v", actual, expect)
+	}
+	if _, err := db.DeleteWhere(&testModel{}, nil); err == nil {
+		t.Errorf("DB.DeleteWhere(nil condition) => nil; want error")
+	}
+	if _, err := db.DeleteWhere(&testModel{}, db.OrderBy("id", ASC)); err == nil {
+		t.Errorf("DB.DeleteWhere(without WHERE) => nil; want error")
+	}
+	cond := db.Where("id", "=", 1)
+	if _, err := db.DeleteWhere(&testModel{}, cond); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := cond.tableName, ""; actual != expect {
+		t.Errorf("DB.DeleteWhere(...); cond.tableName => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_compositePrimaryKey(t *testing.T) {
+	type TestCompositeKey struct {
+		GroupId int64 `db:"pk"`
+		UserId  int64 `db:"pk"`
+		Role    string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS test_composite_key`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTable(&TestCompositeKey{}); err != nil {
+		t.Fatal(err)
+	}
+	objs := []TestCompositeKey{{1, 1, "owner"}, {1, 2, "member"}, {2, 1, "member"}, {2, 2, "owner"}}
+	if _, err := db.Insert(objs); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Insert(&TestCompositeKey{1, 1, "dup"}); err == nil {
+		t.Errorf("DB.Insert(duplicated key) => nil; want error")
+	}
+
+	n, err := db.Update(&TestCompositeKey{1, 2, "owner"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Update(...) => %v; want %v", actual, expect)
+	}
+	n, err = db.Delete([]TestCompositeKey{{1, 1, ""}, {2, 2, ""}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(2); actual != expect {
+		t.Errorf("DB.Delete(...) => %v; want %v", actual, expect)
+	}
+	var actual []TestCompositeKey
+	if err := db.Select(&actual, db.OrderBy("group_id", ASC, "user_id", ASC)); err != nil {
+		t.Fatal(err)
+	}
+	expect := []TestCompositeKey{{1, 2, "owner"}, {2, 1, "member"}}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(...) => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_keyPredicate(t *testing.T) {
+	type testKey struct {
+		A int64 `db:"pk"`
+		B int64 `db:"pk"`
+	}
+	objs := []interface{}{&testKey{1, 2}, &testKey{3, 4}}
+	expectArgs := []interface{}{int64(1), int64(2), int64(3), int64(4)}
+	for _, v := range []struct {
+		dialect Dialect
+		expect  string
+	}{
+		{&SQLite3Dialect{}, `("a" = ? AND "b" = ?) OR ("a" = ? AND "b" = ?)`},
+		{&MySQLDialect{}, "(`a`, `b`) IN ((?, ?), (?, ?))"},
+		{&PostgresDialect{}, `("a", "b") IN (($1, $2), ($3, $4))`},
+	} {
+		db := &DB{dialect: v.dialect}
+		query, args := db.keyPredicate(0, db.modelOf(reflect.TypeOf(testKey{})).pks, objs)
+		if actual, expect := query, v.expect; actual != expect {
+			t.Errorf("%T: DB.keyPredicate(...) => %q; want %q", v.dialect, actual, expect)
+		}
+		if actual, expect := args, expectArgs; !reflect.DeepEqual(actual, expect) {
+			t.Errorf("%T: DB.keyPredicate(...) => %#v; want %#v", v.dialect, actual, expect)
+		}
+	}
+}
+
 func TestDB_SetLogOutput(t *testing.T) {
 	type TestTable struct {
 		Id   int64 `db:"pk"`
@@ -2896,18 +3659,15 @@ func TestDB_SetLogOutput(t *testing.T) {
 	switch os.Getenv("DB") {
 	case "mysql":
 		expected = fmt.Sprintf(
-			"[%[1]s] [0.00ms] INSERT INTO `test_table` (`name`) VALUES (?); [\"test\"]\n"+
-				"[%[1]s] [0.00ms] SELECT LAST_INSERT_ID();\n",
+			"[%[1]s] [0.00ms] INSERT INTO `test_table` (`name`) VALUES (?); [\"test\"]\n",
 			timeFormat)
 	case "postgres":
 		expected = fmt.Sprintf(
-			"[%[1]s] [0.00ms] INSERT INTO \"test_table\" (\"name\") VALUES ($1); [\"test\"]\n"+
-				"[%[1]s] [0.00ms] SELECT lastval();\n",
+			"[%[1]s] [0.00ms] INSERT INTO \"test_table\" (\"name\") VALUES ($1) RETURNING \"id\"; [\"test\"]\n",
 			timeFormat)
 	default:
 		expected = fmt.Sprintf(
-			"[%[1]s] [0.00ms] INSERT INTO \"test_table\" (\"name\") VALUES (?); [\"test\"]\n"+
-				"[%[1]s] [0.00ms] SELECT last_insert_rowid();\n",
+			"[%[1]s] [0.00ms] INSERT INTO \"test_table\" (\"name\") VALUES (?); [\"test\"]\n",
 			timeFormat)
 	}
 	if !reflect.DeepEqual(actual, expected) {
@@ -2982,18 +3742,15 @@ func TestDB_SetLogFormat(t *testing.T) {
 		switch os.Getenv("DB") {
 		case "mysql":
 			expected = fmt.Sprintf(
-				"[INSERT INTO `test_table` (`name`) VALUES (?); [\"test\"]] in 0.00ms. (%[1]s)\n"+
-					"[SELECT LAST_INSERT_ID();] in 0.00ms. (%[1]s)\n",
+				"[INSERT INTO `test_table` (`name`) VALUES (?); [\"test\"]] in 0.00ms. (%[1]s)\n",
 				timeFormat)
 		case "postgres":
 			expected = fmt.Sprintf(
-				"[INSERT INTO \"test_table\" (\"name\") VALUES ($1); [\"test\"]] in 0.00ms. (%[1]s)\n"+
-					"[SELECT lastval();] in 0.00ms. (%[1]s)\n",
+				"[INSERT INTO \"test_table\" (\"name\") VALUES ($1) RETURNING \"id\"; [\"test\"]] in 0.00ms. (%[1]s)\n",
 				timeFormat)
 		default:
 			expected = fmt.Sprintf(
-				"[INSERT INTO \"test_table\" (\"name\") VALUES (?); [\"test\"]] in 0.00ms. (%[1]s)\n"+
-					"[SELECT last_insert_rowid();] in 0.00ms. (%[1]s)\n",
+				"[INSERT INTO \"test_table\" (\"name\") VALUES (?); [\"test\"]] in 0.00ms. (%[1]s)\n",
 				timeFormat)
 		}
 		if !reflect.DeepEqual(actual, expected) {
@@ -3129,102 +3886,72 @@ func TestEmbeddedStructHooks(t *testing.T) {
 	}()
 }
 
-func TestDB_Commit(t *testing.T) {
-	func() {
-		db, err := testDB()
-		if err != nil {
-			t.Fatal(err)
-		}
-		actual := db.Commit()
-		expect := fmt.Errorf("genmai: transaction hasn't been started or already committed or rolled back")
-		if !reflect.DeepEqual(actual, expect) {
-			t.Errorf(`DB.Commit() => %#v; want %#v`, actual, expect)
-		}
-	}()
-
-	func() {
-		db, err := testDB()
-		if err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Begin(); err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Commit(); err != nil {
-			t.Fatal(err)
-		}
-		actual := db.Commit()
-		expect := fmt.Errorf("genmai: transaction hasn't been started or already committed or rolled back")
-		if !reflect.DeepEqual(actual, expect) {
-			t.Errorf(`DB.Commit() => %#v; want %#v`, actual, expect)
-		}
-	}()
-
-	func() {
-		db, err := testDB()
-		if err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Begin(); err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Rollback(); err != nil {
-			t.Fatal(err)
-		}
-		actual := db.Commit()
-		expect := fmt.Errorf("genmai: transaction hasn't been started or already committed or rolled back")
-		if !reflect.DeepEqual(actual, expect) {
-			t.Errorf(`DB.Commit() => %#v; want %#v`, actual, expect)
-		}
-	}()
-}
-
-func TestDB_Rollback(t *testing.T) {
-	func() {
-		db, err := testDB()
-		if err != nil {
-			t.Fatal(err)
-		}
-		actual := db.Rollback()
-		expect := fmt.Errorf("genmai: transaction hasn't been started or already committed or rolled back")
-		if !reflect.DeepEqual(actual, expect) {
-			t.Errorf(`DB.Commit() => %#v; want %#v`, actual, expect)
-		}
-	}()
-
-	func() {
-		db, err := testDB()
-		if err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Begin(); err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Rollback(); err != nil {
-			t.Fatal(err)
-		}
-		actual := db.Rollback()
-		expect := fmt.Errorf("genmai: transaction hasn't been started or already committed or rolled back")
-		if !reflect.DeepEqual(actual, expect) {
-			t.Errorf(`DB.Commit() => %#v; want %#v`, actual, expect)
-		}
-	}()
-
-	func() {
-		db, err := testDB()
-		if err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Begin(); err != nil {
-			t.Fatal(err)
-		}
-		if err := db.Commit(); err != nil {
-			t.Fatal(err)
+func TestDB_Context_cancelled(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text"),
+		`INSERT INTO test_table (id, name) VALUES (1, 'test1')`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
 		}
-		actual := db.Rollback()
-		expect := fmt.Errorf("genmai: transaction hasn't been started or already committed or rolled back")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	for _, v := range []struct {
+		name string
+		fn   func() error
+	}{
+		{"SelectContext", func() error {
+			var results []TestTable
+			return db.SelectContext(ctx, &results)
+		}},
+		{"InsertContext", func() error {
+			_, err := db.InsertContext(ctx, &TestTable{Name: "test2"})
+			return err
+		}},
+		{"UpdateContext", func() error {
+			_, err := db.UpdateContext(ctx, &TestTable{Id: 1, Name: "updated"})
+			return err
+		}},
+		{"DeleteContext", func() error {
+			_, err := db.DeleteContext(ctx, &TestTable{Id: 1})
+			return err
+		}},
+		{"CreateTableIfNotExistsContext", func() error {
+			return db.CreateTableIfNotExistsContext(ctx, &TestTable{})
+		}},
+		{"LastInsertIdContext", func() error {
+			_, err := db.LastInsertIdContext(ctx)
+			return err
+		}},
+		{"BeginTx", func() error {
+			_, err := db.BeginTx(ctx, nil)
+			return err
+		}},
+	} {
+		actual := v.fn()
+		expect := context.Canceled
 		if !reflect.DeepEqual(actual, expect) {
-			t.Errorf(`DB.Commit() => %#v; want %#v`, actual, expect)
+			t.Errorf(`DB.%s(cancelled) => %#v; want %#v`, v.name, actual, expect)
 		}
-	}()
+	}
+	var results []TestTable
+	if err := db.Select(&results); err != nil {
+		t.Fatal(err)
+	}
+	actual := results
+	expect := []TestTable{{Id: 1, Name: "test1"}}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.Select(&results) => %#v; want %#v`, actual, expect)
+	}
 }
@@ -0,0 +1,130 @@
+package genmai
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"runtime"
+)
+
+// Iter is a cursor to iterate over the result rows of Iterate one by one.
+// Iter doesn't hold all rows in memory, so it is suitable for large results.
+// Iter must be closed after use.
+type Iter struct {
+	db           *DB
+	rows         *sql.Rows
+	done         func() error
+	t            reflect.Type // type of struct to scan into.
+	fieldIndexes [][]int
+	dest         []interface{}
+}
+
+// Iterate runs the "SELECT" query and returns an Iter over the results.
+// table must be struct or pointer to struct, and the table name and the
+// columns will be determined from it as well as Select.
+// args are the same as Select. e.g. Condition, From and Distinct.
+//
+//     iter, err := db.Iterate(&User{}, db.Where("active", "=", true))
+//     if err != nil {
+//         return err
+//     }
+//     defer iter.Close()
+//     for iter.Next() {
+//         var u User
+//         if err := iter.Scan(&u); err != nil {
+//             return err
+//         }
+//         // do something.
+//     }
+//     return iter.Err()
+func (db *DB) Iterate(table interface{}, args ...interface{}) (*Iter, error) {
+	return db.IterateContext(context.Background(), table, args...)
+}
+
+// IterateContext is like Iterate, but with context.
+func (db *DB) IterateContext(ctx context.Context, table interface{}, args ...interface{}) (iter *Iter, err error) {
+	defer func() {
+		if e := recover(); e != nil {
+			buf := make([]byte, 4096)
+			n := runtime.Stack(buf, false)
+			err = fmt.Errorf("%v\n%v", e, string(buf[:n]))
+		}
+	}()
+	_, m, err := db.tableValueOf("Iterate", table)
+	if err != nil {
+		return nil, err
+	}
+	t, tableName := m.typ, m.tableName
+	from, fromModel, err := db.fromTable("Iterate", args)
+	if err != nil {
+		return nil, err
+	}
+	if from != "" {
+		tableName, m = from, fromModel
+	}
+	query, values, err := db.selectQuery(tableName, m, args)
+	if err != nil {
+		return nil, err
+	}
+	rows, done, err := db.query(ctx, query, values...)
+	if err != nil {
+		return nil, err
+	}
+	iter = &Iter{db: db, rows: rows, done: done, t: t}
+	columns, err := rows.Columns()
+	if err != nil {
+		iter.Close()
+		return nil, err
+	}
+	if iter.fieldIndexes, err = db.fieldIndexesByColumns(t, columns); err != nil {
+		iter.Close()
+		return nil, err
+	}
+	iter.dest = make([]interface{}, len(columns))
+	return iter, nil
+}
+
+// Next prepares the next row for Scan.
+// It returns false if there is no next row or an error occurred.
+// Err should be consulted to distinguish between the two cases.
+func (it *Iter) Next() bool {
+	return it.rows.Next()
+}
+
+// Scan copies the columns of the current row into the struct that dest points.
+// dest must be pointer to the same struct type as the table given to Iterate.
+func (it *Iter) Scan(dest interface{}) error {
+	rv := reflect.ValueOf(dest)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Type() != it.t {
+		return fmt.Errorf("Scan: argument must be pointer to %v, got %T", it.t, dest)
+	}
+	rv = rv.Elem()
+	for i, index := range it.fieldIndexes {
+		it.dest[i] = rv.FieldByIndex(index).Addr().Interface()
+	}
+	if err := it.rows.Scan(it.dest...); err != nil {
+		return err
+	}
+	it.db.takeSnapshot(it.db.modelOf(it.t), rv)
+	return nil
+}
+
+// Err returns the error that was encountered during iteration.
+func (it *Iter) Err() error {
+	return it.rows.Err()
+}
+
+// Close closes the Iter.
+// Close must be called even if Next returns false, to release the prepared
+// statement. Calling Close more than once is allowed.
+func (it *Iter) Close() error {
+	err := it.rows.Close()
+	if it.done != nil {
+		if e := it.done(); err == nil {
+			err = e
+		}
+		it.done = nil
+	}
+	return err
+}
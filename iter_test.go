@@ -0,0 +1,95 @@
+package genmai
+
+import (
+	"fmt"
+	"reflect"
+	"testing"
+)
+
+func TestDB_Iterate(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_model_alt`,
+		createTableString("test_model_alt", "name text not null", "addr text not null"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	for _, v := range []struct {
+		args   []interface{}
+		expect []testModel
+	}{
+		{nil, []testModel{
+			{1, "test1", "addr1"},
+			{2, "test2", "addr2"},
+			{3, "test3", "addr3"},
+			{4, "other", "addr4"},
+			{5, "other", "addr5"},
+			{6, "dup", "dup_addr"},
+			{7, "dup", "dup_addr"},
+			{8, "other1", "addr8"},
+			{9, "other2", "addr9"},
+		}},
+		{[]interface{}{db.Where("id", "<", 3).OrderBy("id", DESC)}, []testModel{
+			{2, "test2", "addr2"},
+			{1, "test1", "addr1"},
+		}},
+		{[]interface{}{[]string{"id", "name"}, db.Where("id", "=", 4)}, []testModel{
+			{4, "other", ""},
+		}},
+		{[]interface{}{db.Distinct("name", "addr"), db.Where("name", "=", "dup")}, []testModel{
+			{0, "dup", "dup_addr"},
+		}},
+		{[]interface{}{db.From(&testModelAlt{}), db.Where("id", "=", 1)}, nil},
+	} {
+		iter, err := db.Iterate(&testModel{}, v.args...)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual []testModel
+		for iter.Next() {
+			var m testModel
+			if err := iter.Scan(&m); err != nil {
+				t.Fatal(err)
+			}
+			actual = append(actual, m)
+		}
+		if err := iter.Err(); err != nil {
+			t.Fatal(err)
+		}
+		if err := iter.Close(); err != nil {
+			t.Fatal(err)
+		}
+		expect := v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`DB.Iterate(&testModel{}, %#v) => %#v; want %#v`, v.args, actual, expect)
+		}
+	}
+}
+
+func TestDB_Iterate_error(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var actual interface{}
+	_, actual = db.Iterate([]testModel{})
+	var expect interface{} = fmt.Errorf("Iterate: a table must be struct type, got []genmai.testModel")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.Iterate([]testModel{}) => _, %#v; want %#v`, actual, expect)
+	}
+
+	iter, err := db.Iterate(&testModel{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer iter.Close()
+	if !iter.Next() {
+		t.Fatal(`Iter.Next() => false; want true`)
+	}
+	actual = iter.Scan(&testModelAlt{})
+	expect = fmt.Errorf("Scan: argument must be pointer to genmai.testModel, got *genmai.testModelAlt")
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`Iter.Scan(&testModelAlt{}) => %#v; want %#v`, actual, expect)
+	}
+}
@@ -0,0 +1,409 @@
+package genmai
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+)
+
+// ErrMigrationLocked is returned by Migrate and Rollback when the migrations
+// are locked by another process and the dialect doesn't support the
+// advisory lock to wait for it.
+var ErrMigrationLocked = errors.New("genmai: migrations are locked by another process")
+
+const (
+	// schemaMigrationsTable is the table name to record the applied migrations.
+	schemaMigrationsTable = "schema_migrations"
+
+	// schemaMigrationsLockTable is the table name to lock the migrations if
+	// the dialect doesn't support the advisory lock.
+	schemaMigrationsLockTable = "schema_migrations_lock"
+
+	// migrationLockName is the name of the advisory lock for the migrations.
+	migrationLockName = "genmai_migrations"
+)
+
+// Migration represents a versioned migration.
+type Migration struct {
+	// Version identifies the migration. The migrations are applied in
+	// ascending order of it.
+	Version int64
+
+	// Name is the description of the migration.
+	Name string
+
+	// Up applies the migration.
+	Up func(tx *Tx) error
+
+	// Down reverts the migration. If it is nil, the migration can't be
+	// rolled back.
+	Down func(tx *Tx) error
+}
+
+// MigrationStatus represents the status of a migration.
+type MigrationStatus struct {
+	Version int64
+	Name    string
+
+	// AppliedAt is the time when the migration was applied.
+	// It is nil if the migration hasn't been applied.
+	AppliedAt *time.Time
+
+	// Missing is true if the migration has been applied, but it isn't
+	// registered to the Migrator.
+	Missing bool
+}
+
+// Migrator applies and reverts the registered migrations, and records the
+// applied versions in "schema_migrations" table.
+//
+// Each migration is run in a transaction, and it is never retried even if
+// SetTransactionRetry is set. The DDL is rolled back by a failed migration
+// if the dialect supports the transactional DDL. e.g. SQLite3 and
+// PostgreSQL. On MySQL, the DDL commits the transaction implicitly, so a
+// failed migration may be applied partially.
+//
+// Migrate and Rollback take a lock while running, so that two processes don't
+// migrate the same database at once. The advisory lock of the database is
+// used if the dialect supports it, otherwise a row of
+// "schema_migrations_lock" table is used, and ErrMigrationLocked will be
+// returned without waiting if it has been locked. If the process crashed
+// while holding the lock, the row must be deleted manually.
+type Migrator struct {
+	db         *DB
+	migrations []*Migration
+}
+
+// NewMigrator returns a new Migrator for db.
+func NewMigrator(db *DB) *Migrator {
+	return &Migrator{db: db}
+}
+
+// Register registers a migration of version.
+// down can be nil if the migration is irreversible.
+// Register panics if the version has already been registered or up is nil.
+//
+//     m.Register(1, "create user", func(tx *genmai.Tx) error {
+//         return tx.CreateTable(&User{})
+//     }, func(tx *genmai.Tx) error {
+//         return tx.DropTable(&User{})
+//     })
+func (m *Migrator) Register(version int64, name string, up, down func(tx *Tx) error) {
+	if up == nil {
+		panic(fmt.Errorf("Register: up of migration %d is nil", version))
+	}
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			panic(fmt.Errorf("Register: migration %d is registered twice", version))
+		}
+	}
+	m.migrations = append(m.migrations, &Migration{Version: version, Name: name, Up: up, Down: down})
+	sort.Slice(m.migrations, func(i, j int) bool {
+		return m.migrations[i].Version < m.migrations[j].Version
+	})
+}
+
+// Migrate applies all pending migrations in ascending order of the version.
+// If a migration fails, Migrate stops and returns the error. The migrations
+// that have been applied before it remain applied.
+func (m *Migrator) Migrate() error {
+	return m.MigrateContext(context.Background())
+}
+
+// MigrateContext is like Migrate, but with context.
+func (m *Migrator) MigrateContext(ctx context.Context) error {
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, migration := range m.migrations {
+			if _, ok := applied[migration.Version]; ok {
+				continue
+			}
+			if err := m.run(ctx, migration.Up, func(tx *Tx) error {
+				query := fmt.Sprintf("INSERT INTO %s (%s, %s, %s) VALUES (%s, %s, %s)",
+					tx.dialect.Quote(schemaMigrationsTable),
+					tx.dialect.Quote("version"), tx.dialect.Quote("name"), tx.dialect.Quote("applied_at"),
+					tx.dialect.PlaceHolder(0), tx.dialect.PlaceHolder(1), tx.dialect.PlaceHolder(2))
+				_, err := tx.exec(ctx, query, migration.Version, migration.Name, now())
+				return err
+			}); err != nil {
+				return fmt.Errorf("Migrate: migration %d (%s): %v", migration.Version, migration.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Rollback reverts the last n applied migrations in descending order of the
+// version. If the number of applied migrations is less than n, all of them
+// will be reverted.
+// Rollback returns an error if the migration to revert isn't registered or
+// it doesn't have Down.
+func (m *Migrator) Rollback(n int) error {
+	return m.RollbackContext(context.Background(), n)
+}
+
+// RollbackContext is like Rollback, but with context.
+func (m *Migrator) RollbackContext(ctx context.Context, n int) error {
+	return m.withLock(ctx, func() error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		versions := make([]int64, 0, len(applied))
+		for version := range applied {
+			versions = append(versions, version)
+		}
+		sort.Slice(versions, func(i, j int) bool { return versions[i] > versions[j] })
+		if n < len(versions) {
+			versions = versions[:n]
+		}
+		for _, version := range versions {
+			migration := m.migration(version)
+			if migration == nil {
+				return fmt.Errorf("Rollback: migration %d isn't registered", version)
+			}
+			if migration.Down == nil {
+				return fmt.Errorf("Rollback: migration %d (%s) is irreversible", version, migration.Name)
+			}
+			if err := m.run(ctx, migration.Down, func(tx *Tx) error {
+				query := fmt.Sprintf("DELETE FROM %s WHERE %s = %s",
+					tx.dialect.Quote(schemaMigrationsTable), tx.dialect.Quote("version"), tx.dialect.PlaceHolder(0))
+				_, err := tx.exec(ctx, query, version)
+				return err
+			}); err != nil {
+				return fmt.Errorf("Rollback: migration %d (%s): %v", version, migration.Name, err)
+			}
+		}
+		return nil
+	})
+}
+
+// Status returns the status of the registered migrations and the applied
+// migrations that aren't registered, in ascending order of the version.
+func (m *Migrator) Status() ([]MigrationStatus, error) {
+	return m.StatusContext(context.Background())
+}
+
+// StatusContext is like Status, but with context.
+func (m *Migrator) StatusContext(ctx context.Context) ([]MigrationStatus, error) {
+	if err := m.createTable(ctx); err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var statuses []MigrationStatus
+	for _, migration := range m.migrations {
+		status := MigrationStatus{Version: migration.Version, Name: migration.Name}
+		if s, ok := applied[migration.Version]; ok {
+			status.AppliedAt = s.AppliedAt
+			delete(applied, migration.Version)
+		}
+		statuses = append(statuses, status)
+	}
+	for _, s := range applied {
+		statuses = append(statuses, s)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		return statuses[i].Version < statuses[j].Version
+	})
+	return statuses, nil
+}
+
+// migration returns the registered migration of version.
+// It returns nil if it isn't found.
+func (m *Migrator) migration(version int64) *Migration {
+	for _, migration := range m.migrations {
+		if migration.Version == version {
+			return migration
+		}
+	}
+	return nil
+}
+
+// run calls fn and record in a transaction.
+// The transaction isn't retried, because the DDL that has been committed
+// implicitly can't be run again.
+func (m *Migrator) run(ctx context.Context, fn, record func(tx *Tx) error) error {
+	return m.db.transaction(ctx, nil, func(tx *Tx) error {
+		if err := fn(tx); err != nil {
+			return err
+		}
+		return record(tx)
+	})
+}
+
+// applied returns the statuses of the applied migrations by the version.
+// All of them are marked as missing.
+func (m *Migrator) applied(ctx context.Context) (map[int64]MigrationStatus, error) {
+	query := fmt.Sprintf("SELECT %s, %s, %s FROM %s",
+		m.db.dialect.Quote("version"), m.db.dialect.Quote("name"), m.db.dialect.Quote("applied_at"),
+		m.db.dialect.Quote(schemaMigrationsTable))
+	rows, done, err := m.db.query(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer done()
+	defer rows.Close()
+	applied := make(map[int64]MigrationStatus)
+	for rows.Next() {
+		var s MigrationStatus
+		var appliedAt migrationTime
+		if err := rows.Scan(&s.Version, &s.Name, &appliedAt); err != nil {
+			return nil, err
+		}
+		s.AppliedAt, s.Missing = &appliedAt.Time, true
+		applied[s.Version] = s
+	}
+	return applied, rows.Err()
+}
+
+// migrationTimeLayouts is the layouts of the string representation of
+// "applied_at" column.
+var migrationTimeLayouts = []string{
+	"2006-01-02 15:04:05.999999999-07:00",
+	"2006-01-02 15:04:05.999999999",
+	"2006-01-02T15:04:05.999999999Z07:00",
+}
+
+// migrationTime is time.Time to scan "applied_at" column.
+// It accepts the string representation as well, because the MySQL driver
+// returns DATETIME as []byte unless parseTime=true is given in the DSN.
+type migrationTime struct {
+	time.Time
+}
+
+// Scan implements the database/sql Scanner interface.
+func (t *migrationTime) Scan(src interface{}) (err error) {
+	var s string
+	switch v := src.(type) {
+	case time.Time:
+		t.Time = v
+		return nil
+	case []byte:
+		s = string(v)
+	case string:
+		s = v
+	default:
+		return fmt.Errorf("genmai: unsupported type of applied_at: %T", src)
+	}
+	for _, layout := range migrationTimeLayouts {
+		if t.Time, err = time.ParseInLocation(layout, s, time.UTC); err == nil {
+			return nil
+		}
+	}
+	return err
+}
+
+// createTable creates "schema_migrations" table if it doesn't exist.
+func (m *Migrator) createTable(ctx context.Context) error {
+	d := m.db.dialect
+	integer, _ := d.SQLType(int64(0), false, 0)
+	text, _ := d.SQLType("", false, 0)
+	timestamp, _ := d.SQLType(time.Time{}, false, 0)
+	query := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s %s NOT NULL PRIMARY KEY, %s %s NOT NULL, %s %s NOT NULL)",
+		d.Quote(schemaMigrationsTable),
+		d.Quote("version"), integer,
+		d.Quote("name"), text,
+		d.Quote("applied_at"), timestamp)
+	_, err := m.db.execDirect(ctx, query)
+	return err
+}
+
+// withLock calls fn while holding the lock of the migrations.
+// "schema_migrations" table will be created before fn is called.
+func (m *Migrator) withLock(ctx context.Context, fn func() error) (err error) {
+	if err := m.createTable(ctx); err != nil {
+		return err
+	}
+	d := m.db.dialect
+	if query, args := d.AdvisoryLock(migrationLockName); query != "" {
+		// the advisory lock belongs to the session, so it must be acquired
+		// and released on the same connection.
+		conn, err := m.db.db.Conn(ctx)
+		if err != nil {
+			return err
+		}
+		defer conn.Close()
+		locker := m.db.withTx(nil, conn)
+		if err := advisoryLock(ctx, locker, query, args); err != nil {
+			return err
+		}
+		defer func() {
+			// the lock must be released even if ctx has been canceled.
+			// If it can't be released, the connection is discarded instead
+			// of returning to the pool with the lock.
+			query, args := d.AdvisoryUnlock(migrationLockName)
+			if e := advisoryLock(context.Background(), locker, query, args); e != nil {
+				conn.Raw(func(interface{}) error { return driver.ErrBadConn })
+				if err == nil {
+					err = e
+				}
+			}
+		}()
+		return fn()
+	}
+	integer, _ := d.SQLType(int64(0), false, 0)
+	if _, err := m.db.execDirect(ctx, fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (%s %s NOT NULL PRIMARY KEY)",
+		d.Quote(schemaMigrationsLockTable), d.Quote("id"), integer)); err != nil {
+		return err
+	}
+	if _, err := m.db.execDirect(ctx, fmt.Sprintf("INSERT INTO %s (%s) VALUES (1)",
+		d.Quote(schemaMigrationsLockTable), d.Quote("id"))); err != nil {
+		if m.locked(ctx) {
+			return ErrMigrationLocked
+		}
+		return err
+	}
+	defer func() {
+		// the lock must be released even if ctx has been canceled.
+		if _, e := m.db.execDirect(context.Background(), fmt.Sprintf("DELETE FROM %s", d.Quote(schemaMigrationsLockTable))); err == nil {
+			err = e
+		}
+	}()
+	return fn()
+}
+
+// advisoryLock runs the SQL of Dialect.AdvisoryLock or
+// Dialect.AdvisoryUnlock, and returns an error if its result isn't true.
+func advisoryLock(ctx context.Context, db *DB, query string, args []interface{}) error {
+	rows, done, err := db.query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer rows.Close()
+	var ok sql.NullBool
+	if rows.Next() {
+		if err := rows.Scan(&ok); err != nil {
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	if !ok.Valid || !ok.Bool {
+		return fmt.Errorf("genmai: %s didn't succeed", query)
+	}
+	return nil
+}
+
+// locked returns whether the row of "schema_migrations_lock" table exists.
+func (m *Migrator) locked(ctx context.Context) bool {
+	rows, done, err := m.db.query(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", m.db.dialect.Quote(schemaMigrationsLockTable)))
+	if err != nil {
+		return false
+	}
+	defer done()
+	defer rows.Close()
+	var n int64
+	return rows.Next() && rows.Scan(&n) == nil && n > 0
+}
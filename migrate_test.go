@@ -0,0 +1,267 @@
+package genmai
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+)
+
+type migrationA struct {
+	Id int64 `db:"pk"`
+}
+
+type migrationB struct {
+	Id int64 `db:"pk"`
+}
+
+func testMigrator(t *testing.T) (*DB, *Migrator) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS schema_migrations`,
+		`DROP TABLE IF EXISTS schema_migrations_lock`,
+		`DROP TABLE IF EXISTS migration_a`,
+		`DROP TABLE IF EXISTS migration_b`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	m := NewMigrator(db)
+	m.Register(2, "create migration_b", func(tx *Tx) error {
+		return tx.CreateTable(&migrationB{})
+	}, func(tx *Tx) error {
+		return tx.DropTable(&migrationB{})
+	})
+	m.Register(1, "create migration_a", func(tx *Tx) error {
+		return tx.CreateTable(&migrationA{})
+	}, func(tx *Tx) error {
+		return tx.DropTable(&migrationA{})
+	})
+	return db, m
+}
+
+func testMigrationStatus(t *testing.T, m *Migrator) (applied []int64) {
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, s := range statuses {
+		if s.AppliedAt != nil {
+			applied = append(applied, s.Version)
+		}
+	}
+	return applied
+}
+
+func TestMigrator_Migrate(t *testing.T) {
+	db, m := testMigrator(t)
+	defer db.Close()
+	if actual, expect := testMigrationStatus(t, m), []int64(nil); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migrator.Status() => applied %#v; want %#v", actual, expect)
+	}
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := testMigrationStatus(t, m), []int64{1, 2}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migrator.Migrate() => applied %#v; want %#v", actual, expect)
+	}
+	for _, table := range []interface{}{&[]migrationA{}, &[]migrationB{}} {
+		if err := db.Select(table); err != nil {
+			t.Errorf("Migrator.Migrate() => %T isn't created: %v", table, err)
+		}
+	}
+	// the applied migrations must not be run again.
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+
+	m.Register(3, "failed", func(tx *Tx) error {
+		if err := tx.DropTable(&migrationA{}); err != nil {
+			return err
+		}
+		return errors.New("failed")
+	}, nil)
+	if err := m.Migrate(); err == nil {
+		t.Errorf("Migrator.Migrate() => nil; want error")
+	}
+	if actual, expect := testMigrationStatus(t, m), []int64{1, 2}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migrator.Migrate() => applied %#v; want %#v", actual, expect)
+	}
+	if err := db.Select(&[]migrationA{}); err != nil {
+		t.Errorf("Migrator.Migrate() => failed migration isn't rolled back: %v", err)
+	}
+}
+
+func TestMigrator_Rollback(t *testing.T) {
+	db, m := testMigrator(t)
+	defer db.Close()
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Rollback(1); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := testMigrationStatus(t, m), []int64{1}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migrator.Rollback(1) => applied %#v; want %#v", actual, expect)
+	}
+	if err := db.Select(&[]migrationB{}); err == nil {
+		t.Errorf("Migrator.Rollback(1) => migration_b isn't dropped")
+	}
+	if err := m.Rollback(10); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := testMigrationStatus(t, m), []int64(nil); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migrator.Rollback(10) => applied %#v; want %#v", actual, expect)
+	}
+
+	m.Register(3, "irreversible", func(tx *Tx) error { return nil }, nil)
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Rollback(1); err == nil {
+		t.Errorf("Migrator.Rollback(1) => nil; want error")
+	}
+	if actual, expect := testMigrationStatus(t, m), []int64{1, 2, 3}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migrator.Rollback(1) => applied %#v; want %#v", actual, expect)
+	}
+}
+
+func TestMigrator_Status(t *testing.T) {
+	db, m := testMigrator(t)
+	defer db.Close()
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.db.Exec(`INSERT INTO schema_migrations (version, name, applied_at) VALUES (0, 'removed', CURRENT_TIMESTAMP)`); err != nil {
+		t.Fatal(err)
+	}
+	statuses, err := m.Status()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var actual []MigrationStatus
+	for _, s := range statuses {
+		if s.AppliedAt == nil {
+			t.Errorf("Migrator.Status() => migration %d isn't applied", s.Version)
+		}
+		s.AppliedAt = nil
+		actual = append(actual, s)
+	}
+	expect := []MigrationStatus{
+		{Version: 0, Name: "removed", Missing: true},
+		{Version: 1, Name: "create migration_a"},
+		{Version: 2, Name: "create migration_b"},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("Migrator.Status() => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestMigrator_lock(t *testing.T) {
+	db, m := testMigrator(t)
+	defer db.Close()
+	if query, _ := db.dialect.AdvisoryLock(migrationLockName); query != "" {
+		t.Skip("the dialect uses the advisory lock")
+	}
+	for _, query := range []string{
+		`CREATE TABLE schema_migrations_lock (id integer NOT NULL PRIMARY KEY)`,
+		`INSERT INTO schema_migrations_lock (id) VALUES (1)`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := m.Migrate(); err != ErrMigrationLocked {
+		t.Errorf("Migrator.Migrate() => %#v; want %#v", err, ErrMigrationLocked)
+	}
+	if _, err := db.db.Exec(`DELETE FROM schema_migrations_lock`); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Migrate(); err != nil {
+		t.Fatal(err)
+	}
+	if err := m.Migrate(); err != nil {
+		t.Errorf("Migrator.Migrate() => lock isn't released: %v", err)
+	}
+}
+
+func TestMigrator_lock_cancel(t *testing.T) {
+	db, m := testMigrator(t)
+	defer db.Close()
+	if query, _ := db.dialect.AdvisoryLock(migrationLockName); query != "" {
+		t.Skip("the dialect uses the advisory lock")
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	m.Register(3, "canceled", func(tx *Tx) error {
+		cancel()
+		return ctx.Err()
+	}, nil)
+	if err := m.MigrateContext(ctx); err == nil {
+		t.Errorf("Migrator.MigrateContext(canceled) => nil; want error")
+	}
+	if m.locked(context.Background()) {
+		t.Errorf("Migrator.MigrateContext(canceled) => lock isn't released")
+	}
+}
+
+func Test_migrationTime_Scan(t *testing.T) {
+	expect := time.Date(2014, 1, 2, 3, 4, 5, 600000000, time.UTC)
+	for _, src := range []interface{}{
+		expect,
+		[]byte("2014-01-02 03:04:05.6"),
+		"2014-01-02 03:04:05.600000+00:00",
+		"2014-01-02T03:04:05.6Z",
+	} {
+		var actual migrationTime
+		if err := actual.Scan(src); err != nil {
+			t.Errorf("migrationTime.Scan(%#v) => %#v; want nil", src, err)
+			continue
+		}
+		if !actual.Equal(expect) {
+			t.Errorf("migrationTime.Scan(%#v) => %v; want %v", src, actual.Time, expect)
+		}
+	}
+	for _, src := range []interface{}{"invalid", int64(1)} {
+		var actual migrationTime
+		if err := actual.Scan(src); err == nil {
+			t.Errorf("migrationTime.Scan(%#v) => nil; want error", src)
+		}
+	}
+}
+
+func Test_advisoryLock(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, v := range []struct {
+		query  string
+		expect bool
+	}{
+		{`SELECT 1`, true},
+		{`SELECT 0`, false},
+		{`SELECT NULL`, false},
+	} {
+		err := advisoryLock(context.Background(), db, v.query, nil)
+		if actual, expect := err == nil, v.expect; actual != expect {
+			t.Errorf("advisoryLock(ctx, db, %#v, nil) => %#v; want succeeded %v", v.query, err, expect)
+		}
+	}
+}
+
+func TestMigrator_Register(t *testing.T) {
+	db, m := testMigrator(t)
+	defer db.Close()
+	defer func() {
+		if recover() == nil {
+			t.Errorf("Migrator.Register(duplicated) => no panic; want panic")
+		}
+	}()
+	m.Register(1, "duplicated", func(tx *Tx) error { return nil }, nil)
+}
@@ -0,0 +1,203 @@
+package genmai
+
+import (
+	"reflect"
+	"sync"
+
+	"github.com/naoina/go-stringutil"
+)
+
+// modelCache is the cache of model for each struct type.
+var modelCache sync.Map // map[reflect.Type]*model
+
+// model represents the metadata of a struct that is mapped to a table.
+// It is computed once for each struct type by modelOf, so the CRUD
+// operations don't need to inspect the struct by reflection every time.
+type model struct {
+	typ       reflect.Type
+	tableName string
+
+	// fields that are mapped to the columns, in order of definition.
+	// Fields of embedded structs are expanded.
+	// Unexported fields and fields that have skip tag are excluded.
+	fields []*modelField
+
+	// fields that are used as values of INSERT and UPDATE.
+	// i.e. fields excluding the auto-incrementable primary key.
+	valueFields []*modelField
+
+	// fields of the primary key, in order of definition.
+	// More than one field means a composite primary key.
+	pks []*modelField
+
+	// field for the optimistic locking. nil if it isn't defined.
+	version *modelField
+
+	// field for the soft delete. nil if it isn't defined.
+	softDelete *modelField
+
+	// fields to identify the record on update and delete.
+	// i.e. pks and version.
+	keys []*modelField
+
+	// nested field indexes by column name, to scan the result rows.
+	indexes map[string][]int
+}
+
+// modelField represents the metadata of a field that is mapped to a column.
+type modelField struct {
+	field  reflect.StructField
+	index  []int    // nested index for FieldByIndex.
+	column string   // column name.
+	tags   []string // options of "db" tag.
+
+	pk            bool
+	unique        bool
+	version       bool
+	softDelete    bool
+	autoIncrement bool // whether the field is the auto-incrementable single primary key.
+
+	size    uint64 // value of "size" tag.
+	sizeErr error  // error of parsing the "size" tag.
+	def     string // value of "default" tag.
+}
+
+// modelOf returns the model of the struct type t.
+func (db *DB) modelOf(t reflect.Type) *model {
+	if m, ok := modelCache.Load(t); ok {
+		return m.(*model)
+	}
+	m := &model{
+		typ:       t,
+		tableName: db.tableNameOf(t),
+		indexes:   make(map[string][]int),
+	}
+	db.collectModelFields(m, t, nil)
+	db.collectColumnIndexes(m, t, nil)
+	for _, f := range m.fields {
+		if f.pk {
+			m.pks = append(m.pks, f)
+		}
+		if f.version && m.version == nil {
+			m.version = f
+		}
+		if f.softDelete && m.softDelete == nil {
+			m.softDelete = f
+		}
+	}
+	m.keys = append(m.keys, m.pks...)
+	if m.version != nil {
+		m.keys = append(m.keys, m.version)
+	}
+	if len(m.pks) > 1 {
+		// a column of the composite primary key cannot be auto-incremented.
+		for _, f := range m.pks {
+			f.autoIncrement = false
+		}
+	}
+	for _, f := range m.fields {
+		if !f.autoIncrement {
+			m.valueFields = append(m.valueFields, f)
+		}
+	}
+	actual, _ := modelCache.LoadOrStore(t, m)
+	return actual.(*model)
+}
+
+// autoIncrementPK returns the field of the auto-incrementable primary key.
+// If it isn't defined, it returns nil.
+func (m *model) autoIncrementPK() *modelField {
+	if len(m.pks) == 1 && m.pks[0].autoIncrement {
+		return m.pks[0]
+	}
+	return nil
+}
+
+// fieldByName returns the field of the struct field name.
+// If it isn't found, it returns nil.
+func (m *model) fieldByName(name string) *modelField {
+	for _, f := range m.fields {
+		if f.field.Name == name {
+			return f
+		}
+	}
+	return nil
+}
+
+// fieldByColumn returns the field of the column.
+// If it isn't found, it returns nil.
+func (m *model) fieldByColumn(column string) *modelField {
+	for _, f := range m.fields {
+		if f.column == column {
+			return f
+		}
+	}
+	return nil
+}
+
+// collectModelFields collects the fields of t into m.fields recursively.
+func (db *DB) collectModelFields(m *model, t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if IsUnexportedField(field) || db.hasSkipTag(&field) {
+			continue
+		}
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(idx)-1] = i
+		if field.Anonymous {
+			db.collectModelFields(m, field.Type, idx)
+			continue
+		}
+		f := &modelField{
+			field:  field,
+			index:  idx,
+			column: db.columnFromTag(field),
+			tags:   db.tagsFromField(&field),
+			def:    field.Tag.Get(dbDefaultTag),
+		}
+		for _, tag := range f.tags {
+			switch tag {
+			case "pk":
+				f.pk = true
+				f.autoIncrement = db.isAutoIncrementable(&field)
+			case "unique":
+				f.unique = true
+			case "version":
+				f.version = true
+			case "softdelete":
+				f.softDelete = true
+			}
+		}
+		f.size, f.sizeErr = db.sizeFromTag(&field)
+		m.fields = append(m.fields, f)
+	}
+}
+
+// collectColumnIndexes collects the nested field indexes of t by column name
+// into m.indexes recursively. If a column name is duplicated, the first
+// found field in depth-first order is used.
+func (db *DB) collectColumnIndexes(m *model, t reflect.Type, index []int) {
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		idx := make([]int, len(index)+1)
+		copy(idx, index)
+		idx[len(idx)-1] = i
+		if column := db.columnFromTag(field); m.indexes[column] == nil {
+			m.indexes[column] = idx
+		}
+		if field.Anonymous && field.Type.Kind() == reflect.Struct {
+			db.collectColumnIndexes(m, field.Type, idx)
+		}
+	}
+}
+
+// tableNameOf returns the table name of t.
+// If t implements TableNamer, it returns the result of TableName.
+// Otherwise, it returns snake-cased name of t.
+func (db *DB) tableNameOf(t reflect.Type) string {
+	if table, ok := reflect.New(t).Interface().(TableNamer); ok {
+		return table.TableName()
+	}
+	return stringutil.ToSnakeCase(t.Name())
+}
@@ -0,0 +1,53 @@
+package genmai
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestDB_modelOf(t *testing.T) {
+	db := &DB{}
+	type Embedded struct {
+		Created string `column:"created_at"`
+	}
+	type testUser struct {
+		Id       int64  `db:"pk"`
+		Name     string `db:"unique" size:"32"`
+		Bio      string `default:"none"`
+		Ignored  string `db:"-"`
+		internal string
+		Embedded
+	}
+	typ := reflect.TypeOf(testUser{})
+	m := db.modelOf(typ)
+	if actual, expect := m.tableName, "test_user"; actual != expect {
+		t.Errorf("tableName => %q; want %q", actual, expect)
+	}
+	var columns []string
+	for _, f := range m.fields {
+		columns = append(columns, f.column)
+	}
+	if actual, expect := columns, []string{"id", "name", "bio", "created_at"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("columns => %#v; want %#v", actual, expect)
+	}
+	var values []string
+	for _, f := range m.valueFields {
+		values = append(values, f.column)
+	}
+	if actual, expect := values, []string{"name", "bio", "created_at"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("value columns => %#v; want %#v", actual, expect)
+	}
+	if pk := m.autoIncrementPK(); pk == nil || pk.column != "id" {
+		t.Errorf("autoIncrementPK() => %#v; want id", pk)
+	}
+	name := m.fields[1]
+	if actual, expect := []interface{}{name.unique, name.size, m.fields[2].def}, []interface{}{true, uint64(32), "none"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("unique, size, default => %#v; want %#v", actual, expect)
+	}
+	if actual, expect := m.indexes["created_at"], []int{5, 0}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`indexes["created_at"] => %#v; want %#v`, actual, expect)
+	}
+	if actual, expect := db.modelOf(typ), m; actual != expect {
+		t.Errorf("modelOf(%v) => %p; want cached %p", typ, actual, expect)
+	}
+}
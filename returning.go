@@ -0,0 +1,189 @@
+package genmai
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// SetReturning sets whether Insert, Update and Delete write back the values
+// that were actually stored in the database to the given struct, by the
+// "RETURNING" clause. It is useful to get the values of DEFAULT columns,
+// serials and the columns that are modified by triggers.
+// By default, it is false.
+// It is ignored if the dialect doesn't support the "RETURNING" clause.
+// e.g. PostgreSQL and SQLite3 (3.35.0 or later) support it, MySQL doesn't.
+// It can be overridden for each call by WithReturning.
+func (db *DB) SetReturning(returning bool) {
+	db.returning = returning
+}
+
+// WithReturning returns a copy of ctx that overrides the setting of
+// SetReturning for the queries that are run with it.
+func WithReturning(ctx context.Context, returning bool) context.Context {
+	return context.WithValue(ctx, returningKey{}, returning)
+}
+
+// returningKey is the context key for WithReturning.
+type returningKey struct{}
+
+// returningClause returns the "RETURNING" clause of all columns of m if it is
+// enabled for ctx and the dialect supports it. Otherwise, it returns empty
+// string.
+func (db *DB) returningClause(ctx context.Context, m *model) string {
+	returning, ok := ctx.Value(returningKey{}).(bool)
+	if !ok {
+		returning = db.returning
+	}
+	if !returning {
+		return ""
+	}
+	columns := make([]string, len(m.fields))
+	for i, f := range m.fields {
+		columns[i] = f.column
+	}
+	return db.dialect.Returning(columns)
+}
+
+// queryReturning runs the query that has the "RETURNING" clause of the
+// columns of fields, and calls fn with each returned row in order.
+// Only fields of the row are set.
+// It returns the number of returned rows as the number of affected rows.
+func (db *DB) queryReturning(ctx context.Context, m *model, fields []*modelField, query string, args []interface{}, fn func(i int, row reflect.Value)) (affected int64, err error) {
+	rows, done, err := db.query(ctx, query, args...)
+	if err != nil {
+		return -1, err
+	}
+	defer done()
+	defer rows.Close()
+	dest := make([]interface{}, len(fields))
+	for ; rows.Next(); affected++ {
+		row := reflect.New(m.typ).Elem()
+		for i, f := range fields {
+			dest[i] = row.FieldByIndex(f.index).Addr().Interface()
+		}
+		if err := rows.Scan(dest...); err != nil {
+			return affected, err
+		}
+		fn(int(affected), row)
+	}
+	return affected, rows.Err()
+}
+
+// insertReturning inserts objs by the query that has the "RETURNING" clause
+// of the columns of fields, and calls fn with each element of objs and the
+// returned row of it.
+// The order of the returned rows isn't guaranteed, so they are matched to
+// objs by the values of returningKeys. If there are no such keys, objs are
+// inserted row by row in a transaction unless the DB is already bound to a
+// transaction.
+func (db *DB) insertReturning(ctx context.Context, m *model, objs []interface{}, fields []*modelField, fn func(rv, row reflect.Value)) (affected int64, err error) {
+	keys := returningKeys(m, objs)
+	if len(objs) > 1 && keys == nil {
+		if !db.inTx() {
+			err := db.TransactionContext(ctx, nil, func(tx *Tx) (err error) {
+				affected, err = tx.insertReturning(ctx, m, objs, fields, fn)
+				return err
+			})
+			return affected, err
+		}
+		for _, obj := range objs {
+			n, err := db.insertReturning(ctx, m, []interface{}{obj}, fields, fn)
+			if err != nil {
+				return affected, err
+			}
+			affected += n
+		}
+		return affected, nil
+	}
+	returned := append([]*modelField{}, fields...)
+	columns := make([]string, 0, len(fields)+len(keys))
+	for _, f := range fields {
+		columns = append(columns, f.column)
+	}
+Keys:
+	for _, key := range keys {
+		for _, f := range fields {
+			if f == key {
+				continue Keys
+			}
+		}
+		returned = append(returned, key)
+		columns = append(columns, key.column)
+	}
+	objsByKey := make(map[string]reflect.Value, len(objs))
+	for _, obj := range objs {
+		rv := reflect.Indirect(reflect.ValueOf(obj))
+		objsByKey[fieldsKey(keys, rv)] = rv
+	}
+	query, args := db.insertQuery(m, objs)
+	query = fmt.Sprintf("%s %s", query, db.dialect.Returning(columns))
+	return db.queryReturning(ctx, m, returned, query, args, func(i int, row reflect.Value) {
+		if rv, ok := objsByKey[fieldsKey(keys, row)]; ok {
+			fn(rv, row)
+		}
+	})
+}
+
+// returningKeys returns the fields to match the rows that are returned by
+// the bulk insert of objs to objs.
+// They are the primary key if it isn't auto-incremented, or else the first
+// unique field that has no NULL values in objs. If there are no such fields,
+// it returns nil.
+func returningKeys(m *model, objs []interface{}) []*modelField {
+	if len(m.pks) > 0 && m.autoIncrementPK() == nil {
+		return m.pks
+	}
+Fields:
+	for _, f := range m.valueFields {
+		if !f.unique {
+			continue
+		}
+		for _, obj := range objs {
+			if isNullValue(reflect.Indirect(reflect.ValueOf(obj)).FieldByIndex(f.index)) {
+				continue Fields
+			}
+		}
+		return []*modelField{f}
+	}
+	return nil
+}
+
+// isNullValue returns whether v will be stored as NULL.
+func isNullValue(v reflect.Value) bool {
+	if v.Kind() == reflect.Ptr && v.IsNil() {
+		return true
+	}
+	if valuer, ok := v.Interface().(driver.Valuer); ok {
+		value, err := valuer.Value()
+		return err != nil || value == nil
+	}
+	return false
+}
+
+// writeBack copies the values of the fields of row to rv.
+// If rv isn't addressable, it does nothing.
+func writeBack(m *model, rv, row reflect.Value) {
+	if !rv.CanAddr() {
+		return
+	}
+	for _, f := range m.fields {
+		rv.FieldByIndex(f.index).Set(row.FieldByIndex(f.index))
+	}
+}
+
+// pkKey returns the string that identifies the primary key values of rv.
+func pkKey(m *model, rv reflect.Value) string {
+	return fieldsKey(m.pks, rv)
+}
+
+// fieldsKey returns the string that identifies the values of fields of rv.
+func fieldsKey(fields []*modelField, rv reflect.Value) string {
+	values := make([]string, len(fields))
+	for i, f := range fields {
+		values[i] = fmt.Sprint(reflect.Indirect(rv.FieldByIndex(f.index)))
+	}
+	return strings.Join(values, "\x00")
+}
@@ -0,0 +1,152 @@
+package genmai
+
+import (
+	"context"
+	"reflect"
+	"testing"
+)
+
+func TestDB_SetReturning(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+		Code string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if db.dialect.Returning([]string{"id"}) == "" {
+		t.Skip("dialect doesn't support RETURNING")
+	}
+	// a text of digits will be stored as integer.
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text", "code integer"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.SetReturning(true)
+
+	obj := &TestTable{Name: "test1", Code: "007"}
+	if _, err := db.Insert(obj); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := obj, (&TestTable{Id: 1, Name: "test1", Code: "7"}); !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Insert(obj); obj => %#v; want %#v", actual, expect)
+	}
+	objs := []TestTable{{Name: "test2", Code: "02"}, {Name: "test3", Code: "03"}}
+	n, err := db.Insert(objs)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(2); actual != expect {
+		t.Errorf("DB.Insert(objs) => %v; want %v", actual, expect)
+	}
+	if actual, expect := objs, []TestTable{{2, "test2", "2"}, {3, "test3", "3"}}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Insert(objs); objs => %#v; want %#v", actual, expect)
+	}
+
+	obj.Code = "0042"
+	if n, err = db.Update(obj); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(1); actual != expect {
+		t.Errorf("DB.Update(obj) => %v; want %v", actual, expect)
+	}
+	if actual, expect := obj.Code, "42"; actual != expect {
+		t.Errorf("DB.Update(obj); obj.Code => %#v; want %#v", actual, expect)
+	}
+
+	// overridden by context.
+	obj.Code = "0043"
+	if _, err = db.UpdateContext(WithReturning(context.Background(), false), obj); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := obj.Code, "0043"; actual != expect {
+		t.Errorf("DB.UpdateContext(WithReturning(ctx, false), obj); obj.Code => %#v; want %#v", actual, expect)
+	}
+
+	deleted := []*TestTable{{Id: 3}, {Id: 1}}
+	if n, err = db.Delete(deleted); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := n, int64(2); actual != expect {
+		t.Errorf("DB.Delete(objs) => %v; want %v", actual, expect)
+	}
+	actual := []TestTable{*deleted[0], *deleted[1]}
+	expect := []TestTable{{3, "test3", "3"}, {1, "test1", "43"}}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Delete(objs); objs => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_SetReturning_bulkInsert(t *testing.T) {
+	type TestTable struct {
+		Id    int64  `db:"pk"`
+		Email string `db:"unique"`
+		Code  string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if db.dialect.Returning([]string{"id"}) == "" {
+		t.Skip("dialect doesn't support RETURNING")
+	}
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "email varchar(255) UNIQUE", "code integer"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	db.SetReturning(true)
+	objs := []TestTable{{Email: "a@example.com", Code: "01"}, {Email: "b@example.com", Code: "02"}}
+	if _, err := db.Insert(objs); err != nil {
+		t.Fatal(err)
+	}
+	var rows []TestTable
+	if err := db.Select(&rows, db.OrderBy("id", ASC)); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := objs, rows; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Insert(objs); objs => %#v; want %#v", actual, expect)
+	}
+}
+
+func Test_returningKeys(t *testing.T) {
+	type unique struct {
+		Id    int64 `db:"pk"`
+		Name  string
+		Email *string `db:"unique"`
+	}
+	type naturalPK struct {
+		Code string `db:"pk"`
+		Name string
+	}
+	db := &DB{}
+	email := "a@example.com"
+	for _, v := range []struct {
+		objs   []interface{}
+		expect []string
+	}{
+		{[]interface{}{&unique{Email: &email}, &unique{Email: &email}}, []string{"email"}},
+		{[]interface{}{&unique{Email: &email}, &unique{}}, nil},
+		{[]interface{}{&naturalPK{Code: "a"}, &naturalPK{Code: "b"}}, []string{"code"}},
+	} {
+		m := db.modelOf(reflect.TypeOf(v.objs[0]).Elem())
+		var actual []string
+		for _, f := range returningKeys(m, v.objs) {
+			actual = append(actual, f.column)
+		}
+		if !reflect.DeepEqual(actual, v.expect) {
+			t.Errorf("returningKeys(%#v) => %#v; want %#v", v.objs, actual, v.expect)
+		}
+	}
+}
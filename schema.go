@@ -0,0 +1,262 @@
+package genmai
+
+import (
+	"context"
+	"database/sql"
+	"regexp"
+	"strings"
+)
+
+// Column represents a column of the table in the database.
+type Column struct {
+	Name string
+
+	// Type is the SQL type of the column as reported by the database.
+	// e.g. "varchar(255)"
+	Type string
+
+	Nullable bool
+
+	// Default is the expression of the default value.
+	// It is nil if the default value isn't defined.
+	Default *string
+}
+
+// Index represents an index of the table in the database.
+type Index struct {
+	Name    string
+	Unique  bool
+	Columns []string
+}
+
+// UniqueConstraint represents a unique constraint of the table in the database.
+type UniqueConstraint struct {
+	Name    string
+	Columns []string
+}
+
+// ForeignKey represents a foreign key constraint of the table in the database.
+type ForeignKey struct {
+	Name    string
+	Columns []string
+
+	// RefTable is the name of the referenced table.
+	RefTable string
+
+	// RefColumns is the referenced columns in order of Columns.
+	RefColumns []string
+}
+
+// Table represents the schema of a table in the database.
+type Table struct {
+	Name              string
+	Columns           []Column
+	PrimaryKey        []string
+	Indexes           []Index
+	UniqueConstraints []UniqueConstraint
+	ForeignKeys       []ForeignKey
+}
+
+// Tables returns the names of the tables in the current database, or the
+// current schema for PostgreSQL, in order of the name.
+func (db *DB) Tables() ([]string, error) {
+	return db.TablesContext(context.Background())
+}
+
+// TablesContext is like Tables, but with context.
+func (db *DB) TablesContext(ctx context.Context) ([]string, error) {
+	tables := []string{}
+	var name string
+	err := db.queryRows(ctx, db.dialect.Tables(), nil, []interface{}{&name}, func() {
+		tables = append(tables, name)
+	})
+	return tables, err
+}
+
+// DescribeTable returns the schema of the table.
+// It returns nil if the table doesn't exist.
+func (db *DB) DescribeTable(table string) (*Table, error) {
+	return db.DescribeTableContext(context.Background(), table)
+}
+
+// DescribeTableContext is like DescribeTable, but with context.
+func (db *DB) DescribeTableContext(ctx context.Context, table string) (t *Table, err error) {
+	t = &Table{Name: table}
+	if t.Columns, err = db.ColumnsContext(ctx, table); err != nil {
+		return nil, err
+	}
+	if len(t.Columns) < 1 {
+		return nil, nil
+	}
+	if t.PrimaryKey, err = db.PrimaryKeyContext(ctx, table); err != nil {
+		return nil, err
+	}
+	if t.Indexes, err = db.IndexesContext(ctx, table); err != nil {
+		return nil, err
+	}
+	if t.UniqueConstraints, err = db.UniqueConstraintsContext(ctx, table); err != nil {
+		return nil, err
+	}
+	if t.ForeignKeys, err = db.ForeignKeysContext(ctx, table); err != nil {
+		return nil, err
+	}
+	return t, nil
+}
+
+// Columns returns the columns of the table in order of definition.
+// It returns an empty slice if the table doesn't exist.
+func (db *DB) Columns(table string) ([]Column, error) {
+	return db.ColumnsContext(context.Background(), table)
+}
+
+// ColumnsContext is like Columns, but with context.
+func (db *DB) ColumnsContext(ctx context.Context, table string) ([]Column, error) {
+	columns := []Column{}
+	var c Column
+	var def sql.NullString
+	query, args := db.dialect.Columns(table)
+	err := db.queryRows(ctx, query, args, []interface{}{&c.Name, &c.Type, &c.Nullable, &def}, func() {
+		if c.Default = nil; def.Valid {
+			s := def.String
+			c.Default = &s
+		}
+		columns = append(columns, c)
+	})
+	return columns, err
+}
+
+// PrimaryKey returns the column names of the primary key of the table in
+// order of the position in the key.
+func (db *DB) PrimaryKey(table string) ([]string, error) {
+	return db.PrimaryKeyContext(context.Background(), table)
+}
+
+// PrimaryKeyContext is like PrimaryKey, but with context.
+func (db *DB) PrimaryKeyContext(ctx context.Context, table string) ([]string, error) {
+	columns := []string{}
+	var column string
+	query, args := db.dialect.PrimaryKey(table)
+	err := db.queryRows(ctx, query, args, []interface{}{&column}, func() {
+		columns = append(columns, column)
+	})
+	return columns, err
+}
+
+// Indexes returns the indexes of the table excluding the primary key, in
+// order of the index name.
+// The unique constraints are also returned as the unique indexes if the
+// database creates the indexes for them.
+func (db *DB) Indexes(table string) ([]Index, error) {
+	return db.IndexesContext(context.Background(), table)
+}
+
+// IndexesContext is like Indexes, but with context.
+func (db *DB) IndexesContext(ctx context.Context, table string) ([]Index, error) {
+	indexes := []Index{}
+	var name, column string
+	var unique bool
+	query, args := db.dialect.Indexes(table)
+	err := db.queryRows(ctx, query, args, []interface{}{&name, &unique, &column}, func() {
+		if n := len(indexes); n > 0 && indexes[n-1].Name == name {
+			indexes[n-1].Columns = append(indexes[n-1].Columns, column)
+			return
+		}
+		indexes = append(indexes, Index{Name: name, Unique: unique, Columns: []string{column}})
+	})
+	return indexes, err
+}
+
+// UniqueConstraints returns the unique constraints of the table in order of
+// the constraint name.
+func (db *DB) UniqueConstraints(table string) ([]UniqueConstraint, error) {
+	return db.UniqueConstraintsContext(context.Background(), table)
+}
+
+// UniqueConstraintsContext is like UniqueConstraints, but with context.
+func (db *DB) UniqueConstraintsContext(ctx context.Context, table string) ([]UniqueConstraint, error) {
+	constraints := []UniqueConstraint{}
+	var name, column string
+	query, args := db.dialect.UniqueConstraints(table)
+	err := db.queryRows(ctx, query, args, []interface{}{&name, &column}, func() {
+		if n := len(constraints); n > 0 && constraints[n-1].Name == name {
+			constraints[n-1].Columns = append(constraints[n-1].Columns, column)
+			return
+		}
+		constraints = append(constraints, UniqueConstraint{Name: name, Columns: []string{column}})
+	})
+	return constraints, err
+}
+
+// ForeignKeys returns the foreign keys of the table in order of the
+// constraint name.
+func (db *DB) ForeignKeys(table string) ([]ForeignKey, error) {
+	return db.ForeignKeysContext(context.Background(), table)
+}
+
+// ForeignKeysContext is like ForeignKeys, but with context.
+func (db *DB) ForeignKeysContext(ctx context.Context, table string) ([]ForeignKey, error) {
+	fks := []ForeignKey{}
+	var name, column, refTable, refColumn string
+	query, args := db.dialect.ForeignKeys(table)
+	err := db.queryRows(ctx, query, args, []interface{}{&name, &column, &refTable, &refColumn}, func() {
+		if n := len(fks); n > 0 && fks[n-1].Name == name {
+			fks[n-1].Columns = append(fks[n-1].Columns, column)
+			fks[n-1].RefColumns = append(fks[n-1].RefColumns, refColumn)
+			return
+		}
+		fks = append(fks, ForeignKey{Name: name, Columns: []string{column}, RefTable: refTable, RefColumns: []string{refColumn}})
+	})
+	return fks, err
+}
+
+// queryRows runs the query with args, and calls fn after each row is scanned
+// into dest.
+func (db *DB) queryRows(ctx context.Context, query string, args []interface{}, dest []interface{}, fn func()) error {
+	rows, done, err := db.query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer rows.Close()
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		fn()
+	}
+	return rows.Err()
+}
+
+var (
+	// display width of the integer types of MySQL. e.g. "int(11)"
+	intDisplayWidthRegexp = regexp.MustCompile(`^(smallint|mediumint|int|bigint)\(\d+\)`)
+
+	// aliases of the SQL types that are reported by the databases.
+	sqlTypeAliases = map[string]string{
+		"tinyint(1)":        "boolean",
+		"bool":              "boolean",
+		"character varying": "varchar",
+		"smallserial":       "smallint",
+		"serial":            "integer",
+		"bigserial":         "bigint",
+	}
+)
+
+// normalizeSQLType returns the canonical form of the SQL type typ to compare
+// the types that are returned by Dialect.SQLType and the database.
+func normalizeSQLType(typ string) string {
+	typ = strings.ToLower(strings.TrimSpace(typ))
+	typ = strings.Replace(typ, ", ", ",", -1)
+	for alias, name := range sqlTypeAliases {
+		if typ == alias || strings.HasPrefix(typ, alias+"(") {
+			typ = name + typ[len(alias):]
+			break
+		}
+	}
+	return intDisplayWidthRegexp.ReplaceAllString(typ, "$1")
+}
+
+// sameSQLType returns whether the SQL types a and b are the same.
+func sameSQLType(a, b string) bool {
+	return normalizeSQLType(a) == normalizeSQLType(b)
+}
@@ -0,0 +1,207 @@
+package genmai
+
+import (
+	"os"
+	"reflect"
+	"testing"
+)
+
+func TestDB_Columns(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_columns`,
+		`CREATE TABLE test_columns (id integer PRIMARY KEY NOT NULL, name varchar(255) NOT NULL DEFAULT 'none', note text)`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.db.Exec(`DROP TABLE IF EXISTS test_columns`)
+	actual, err := db.Columns("test_columns")
+	if err != nil {
+		t.Fatal(err)
+	}
+	def, integer := "'none'", "integer"
+	switch os.Getenv("DB") {
+	case "mysql":
+		def, integer = "none", "int"
+	case "postgres":
+		def = "'none'::character varying"
+	}
+	expect := []Column{
+		{Name: "id", Type: integer},
+		{Name: "name", Type: "varchar(255)", Default: &def},
+		{Name: "note", Type: "text", Nullable: true},
+	}
+	for i := range actual {
+		actual[i].Type = normalizeSQLType(actual[i].Type)
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Columns(%q) => %#v; want %#v", "test_columns", actual, expect)
+	}
+	if actual, err = db.Columns("not_exists"); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []Column{}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Columns(%q) => %#v; want %#v", "not_exists", actual, expect)
+	}
+}
+
+func TestDB_Indexes(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_indexes`,
+		`CREATE TABLE test_indexes (id integer PRIMARY KEY NOT NULL, a varchar(255), b varchar(255), c varchar(255), CONSTRAINT test_indexes_c UNIQUE (c))`,
+		`CREATE INDEX index_a_b ON test_indexes (a, b)`,
+		`CREATE UNIQUE INDEX index_b ON test_indexes (b)`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	defer db.db.Exec(`DROP TABLE IF EXISTS test_indexes`)
+	actual, err := db.Indexes("test_indexes")
+	if err != nil {
+		t.Fatal(err)
+	}
+	// SQLite3 doesn't keep the name of the constraint.
+	unique := "test_indexes_c"
+	if _, ok := db.dialect.(*SQLite3Dialect); ok {
+		unique = "sqlite_autoindex_test_indexes_1"
+	}
+	expect := []Index{
+		{Name: "index_a_b", Columns: []string{"a", "b"}},
+		{Name: "index_b", Unique: true, Columns: []string{"b"}},
+		{Name: unique, Unique: true, Columns: []string{"c"}},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Indexes(%q) => %#v; want %#v", "test_indexes", actual, expect)
+	}
+}
+
+func TestDB_DescribeTable(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	drop := func() {
+		for _, query := range []string{
+			`DROP TABLE IF EXISTS test_member`,
+			`DROP TABLE IF EXISTS test_group`,
+		} {
+			if _, err := db.db.Exec(query); err != nil {
+				t.Fatal(err)
+			}
+		}
+	}
+	drop()
+	defer drop()
+	for _, query := range []string{
+		`CREATE TABLE test_group (id integer NOT NULL, code varchar(255) NOT NULL, PRIMARY KEY (id, code))`,
+		`CREATE TABLE test_member (id integer PRIMARY KEY NOT NULL, group_id integer, group_code varchar(255), email varchar(255),` +
+			` CONSTRAINT test_member_email UNIQUE (email),` +
+			` CONSTRAINT test_member_group FOREIGN KEY (group_id, group_code) REFERENCES test_group (id, code))`,
+		`CREATE INDEX index_test_member_group ON test_member (group_id, group_code)`,
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(err)
+		}
+	}
+	tables, err := db.Tables()
+	if err != nil {
+		t.Fatal(err)
+	}
+	found := 0
+	for _, table := range tables {
+		if table == "test_group" || table == "test_member" {
+			found++
+		}
+	}
+	if actual, expect := found, 2; actual != expect {
+		t.Errorf("DB.Tables() => %#v; want %#v to be contained", tables, []string{"test_group", "test_member"})
+	}
+	pk, err := db.PrimaryKey("test_group")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := pk, []string{"id", "code"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.PrimaryKey(%q) => %#v; want %#v", "test_group", actual, expect)
+	}
+	actual, err := db.DescribeTable("test_member")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := range actual.Columns {
+		actual.Columns[i].Type = normalizeSQLType(actual.Columns[i].Type)
+	}
+	integer, unique, fk := "integer", "test_member_email", "test_member_group"
+	switch os.Getenv("DB") {
+	case "mysql":
+		integer = "int"
+	case "postgres":
+	default:
+		// SQLite3 doesn't keep the names of the constraints.
+		unique, fk = "sqlite_autoindex_test_member_1", "0"
+	}
+	expect := &Table{
+		Name: "test_member",
+		Columns: []Column{
+			{Name: "id", Type: integer},
+			{Name: "group_id", Type: integer, Nullable: true},
+			{Name: "group_code", Type: "varchar(255)", Nullable: true},
+			{Name: "email", Type: "varchar(255)", Nullable: true},
+		},
+		PrimaryKey: []string{"id"},
+		Indexes: []Index{
+			{Name: "index_test_member_group", Columns: []string{"group_id", "group_code"}},
+			{Name: unique, Unique: true, Columns: []string{"email"}},
+		},
+		UniqueConstraints: []UniqueConstraint{
+			{Name: unique, Columns: []string{"email"}},
+		},
+		ForeignKeys: []ForeignKey{
+			{Name: fk, Columns: []string{"group_id", "group_code"}, RefTable: "test_group", RefColumns: []string{"id", "code"}},
+		},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.DescribeTable(%q) => %#v; want %#v", "test_member", actual, expect)
+	}
+	if actual, err = db.DescribeTable("not_exists"); err != nil {
+		t.Fatal(err)
+	}
+	if actual != nil {
+		t.Errorf("DB.DescribeTable(%q) => %#v; want nil", "not_exists", actual)
+	}
+}
+
+func Test_sameSQLType(t *testing.T) {
+	for _, v := range []struct {
+		a, b   string
+		expect bool
+	}{
+		{"integer", "integer", true},
+		{"INT", "int(11)", true},
+		{"BIGINT", "bigint(20)", true},
+		{"BOOLEAN", "tinyint(1)", true},
+		{"DECIMAL(65, 30)", "decimal(65,30)", true},
+		{"varchar(255)", "character varying(255)", true},
+		{"bigserial", "bigint", true},
+		{"varchar(255)", "varchar(10)", false},
+		{"integer", "text", false},
+		{"tinyint(4)", "boolean", false},
+	} {
+		actual := sameSQLType(v.a, v.b)
+		if actual != v.expect {
+			t.Errorf("sameSQLType(%q, %q) => %v; want %v", v.a, v.b, actual, v.expect)
+		}
+	}
+}
@@ -0,0 +1,177 @@
+package genmai
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Unscoped represents an option of Select and Iterate to include the
+// soft-deleted records.
+type Unscoped struct{}
+
+// Unscoped returns an option to include the soft-deleted records.
+// By default, Select and Iterate exclude the records that have been deleted
+// by Delete if the struct has a field that specified "softdelete" struct tag.
+//
+//     db.Select(&users, db.Unscoped(), db.Where("name", "=", "alice"))
+func (db *DB) Unscoped() *Unscoped {
+	return &Unscoped{}
+}
+
+// HardDelete is like Delete, but it always deletes the records physically
+// even if the struct has a field that specified "softdelete" struct tag.
+func (db *DB) HardDelete(obj interface{}) (affected int64, err error) {
+	return db.HardDeleteContext(context.Background(), obj)
+}
+
+// HardDeleteContext is like HardDelete, but with context.
+func (db *DB) HardDeleteContext(ctx context.Context, obj interface{}) (affected int64, err error) {
+	return db.delete(ctx, "HardDelete", obj, true)
+}
+
+// HardDeleteWhere is like DeleteWhere, but it always deletes the records
+// physically even if the struct has a field that specified "softdelete"
+// struct tag.
+func (db *DB) HardDeleteWhere(table interface{}, cond *Condition) (affected int64, err error) {
+	return db.HardDeleteWhereContext(context.Background(), table, cond)
+}
+
+// HardDeleteWhereContext is like HardDeleteWhere, but with context.
+func (db *DB) HardDeleteWhereContext(ctx context.Context, table interface{}, cond *Condition) (affected int64, err error) {
+	return db.deleteWhere(ctx, "HardDeleteWhere", table, cond, true)
+}
+
+// softDelete sets the current time to the soft delete column of the records
+// of objs that haven't been soft-deleted yet.
+func (db *DB) softDelete(ctx context.Context, m *model, objs []interface{}) (affected int64, err error) {
+	if err := checkSoftDelete("Delete", m); err != nil {
+		return -1, err
+	}
+	f := m.softDelete
+	t := now()
+	deletedAt := reflect.ValueOf(&t)
+	column := db.dialect.Quote(f.column)
+	where, args := db.keyPredicate(1, m.keys, objs)
+	query := fmt.Sprintf("UPDATE %s SET %s = %s WHERE (%s) AND %s IS NULL",
+		db.dialect.Quote(m.tableName),
+		column, db.dialect.PlaceHolder(0),
+		where, column)
+	result, err := db.exec(ctx, query, append([]interface{}{deletedAt.Interface()}, args...)...)
+	if err != nil {
+		return -1, err
+	}
+	affected, _ = result.RowsAffected()
+	for _, obj := range objs {
+		if v := reflect.Indirect(reflect.ValueOf(obj)).FieldByIndex(f.index); v.CanSet() {
+			v.Set(deletedAt)
+		}
+	}
+	return affected, nil
+}
+
+// softDeleteWhere sets the current time to the soft delete column of the
+// records that match the cond and haven't been soft-deleted yet.
+func (db *DB) softDeleteWhere(ctx context.Context, name string, m *model, cond *Condition) (affected int64, err error) {
+	if err := checkSoftDelete(name, m); err != nil {
+		return -1, err
+	}
+	f := m.softDelete
+	cond = db.scoped(m.tableName, m, []*Condition{cond})[0]
+	q, a := cond.build(1, false)
+	query := strings.Join(append([]string{
+		"UPDATE", db.dialect.Quote(m.tableName),
+		"SET", fmt.Sprintf("%s = %s", db.dialect.Quote(f.column), db.dialect.PlaceHolder(0)),
+	}, q...), " ")
+	result, err := db.exec(ctx, query, append([]interface{}{now()}, a...)...)
+	if err != nil {
+		return -1, err
+	}
+	affected, _ = result.RowsAffected()
+	return affected, nil
+}
+
+// scoped returns the copy of conditions that exclude the soft-deleted records
+// of the table of m and the joined tables.
+// The given conditions won't be modified.
+func (db *DB) scoped(tableName string, m *model, conditions []*Condition) []*Condition {
+	result := make([]*Condition, len(conditions))
+	whereAt := -1
+	for i, c := range conditions {
+		c2 := &Condition{db: c.db, tableName: c.tableName}
+		for _, p := range c.parts {
+			if jc, ok := p.expr.(*JoinCondition); ok {
+				scoped := *jc
+				scoped.scoped = true
+				p.expr = &scoped
+			}
+			if whereAt < 0 && isWherePart(p) {
+				whereAt = i
+			}
+			c2.parts = append(c2.parts, p)
+		}
+		result[i] = c2
+	}
+	if m == nil || m.softDelete == nil {
+		return result
+	}
+	filter := parts{
+		{clause: Where, expr: &column{table: tableName, name: m.softDelete.column}, priority: 0},
+		{clause: IsNull, priority: 100},
+	}
+	switch {
+	case whereAt >= 0:
+		// WHERE "deleted_at" IS NULL AND (original conditions)
+		c := result[whereAt]
+		var wheres, others parts
+		for _, p := range c.parts {
+			if isWherePart(p) {
+				wheres = append(wheres, p)
+			} else {
+				others = append(others, p)
+			}
+		}
+		c.parts = append(append(others, filter...), part{
+			clause:   And,
+			expr:     &Condition{db: c.db, parts: wheres, tableName: c.tableName},
+			priority: 200,
+		})
+	case len(result) > 0:
+		result[0].parts = append(result[0].parts, filter...)
+	default:
+		result = append(result, &Condition{db: db, parts: filter, tableName: tableName})
+	}
+	return result
+}
+
+// isWherePart returns whether p is a part of "WHERE" clause.
+func isWherePart(p part) bool {
+	return 0 <= p.priority && p.priority < 300
+}
+
+// isUnscoped returns whether the Unscoped option is in args.
+func isUnscoped(args []interface{}) bool {
+	for _, arg := range args {
+		if _, ok := arg.(*Unscoped); ok {
+			return true
+		}
+	}
+	return false
+}
+
+// isSoftDeletable returns whether the field of t can be used for the soft delete.
+// It must be *time.Time, because the records that haven't been deleted are
+// identified by NULL.
+func isSoftDeletable(t reflect.Type) bool {
+	return t.Kind() == reflect.Ptr && t.Elem() == timeType
+}
+
+// checkSoftDelete returns an error if "softdelete" tag of m is specified to
+// the field that can't be used for the soft delete.
+func checkSoftDelete(name string, m *model) error {
+	if m == nil || m.softDelete == nil || isSoftDeletable(m.softDelete.field.Type) {
+		return nil
+	}
+	return fmt.Errorf(`%s: "softdelete" tag must be specified to *time.Time field, got %v`, name, m.softDelete.field.Type)
+}
@@ -0,0 +1,147 @@
+package genmai
+
+import (
+	"container/list"
+	"database/sql"
+	"sync"
+)
+
+// stmt is a prepared statement that is returned by DB.prepare.
+// Close must be called after use instead of Close of *sql.Stmt, because the
+// statement may be owned by the statement cache.
+type stmt struct {
+	*sql.Stmt
+	close func() error
+}
+
+// Close closes the statement, or releases it if it is owned by the statement cache.
+func (s *stmt) Close() error {
+	return s.close()
+}
+
+// StmtCacheStats represents statistics of the prepared statement cache.
+type StmtCacheStats struct {
+	// The number of times the cached statement was used.
+	Hits uint64
+
+	// The number of times the statement wasn't cached and prepared.
+	Misses uint64
+
+	// The number of statements in the cache.
+	Len int
+}
+
+// stmtCache is an LRU cache of prepared statements that keyed by query string.
+type stmtCache struct {
+	size    int
+	hits    uint64
+	misses  uint64
+	ll      *list.List // most recently used at front.
+	entries map[string]*list.Element
+	m       sync.Mutex
+}
+
+// stmtCacheEntry represents a statement in the stmtCache.
+type stmtCacheEntry struct {
+	query   string
+	stmt    *sql.Stmt
+	refs    int  // the number of users of the stmt.
+	evicted bool // whether the entry has been removed from the cache.
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:    size,
+		ll:      list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+// get returns the cached statement of the query.
+// If it isn't cached, get returns nil.
+// The returned entry must be released by release after use.
+func (c *stmtCache) get(query string) *stmtCacheEntry {
+	c.m.Lock()
+	defer c.m.Unlock()
+	elem, ok := c.entries[query]
+	if !ok {
+		c.misses++
+		return nil
+	}
+	c.hits++
+	c.ll.MoveToFront(elem)
+	entry := elem.Value.(*stmtCacheEntry)
+	entry.refs++
+	return entry
+}
+
+// add adds the statement of the query to the cache and returns the entry.
+// If the query has been cached by the other goroutine in the meantime, st
+// will be closed and the cached entry will be returned.
+// The returned entry must be released by release after use.
+func (c *stmtCache) add(query string, st *sql.Stmt) *stmtCacheEntry {
+	c.m.Lock()
+	defer c.m.Unlock()
+	if elem, ok := c.entries[query]; ok {
+		st.Close()
+		entry := elem.Value.(*stmtCacheEntry)
+		entry.refs++
+		return entry
+	}
+	entry := &stmtCacheEntry{query: query, stmt: st, refs: 1}
+	c.entries[query] = c.ll.PushFront(entry)
+	c.evict(c.size)
+	return entry
+}
+
+// release releases the entry that is returned by get or add.
+// The statement will be closed if the entry has been evicted and nobody uses it.
+func (c *stmtCache) release(entry *stmtCacheEntry) error {
+	c.m.Lock()
+	defer c.m.Unlock()
+	entry.refs--
+	if entry.evicted && entry.refs == 0 {
+		return entry.stmt.Close()
+	}
+	return nil
+}
+
+// resize changes the maximum number of statements in the cache.
+func (c *stmtCache) resize(size int) {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.size = size
+	c.evict(size)
+}
+
+// purge removes all statements from the cache.
+func (c *stmtCache) purge() {
+	c.m.Lock()
+	defer c.m.Unlock()
+	c.evict(0)
+}
+
+func (c *stmtCache) stats() StmtCacheStats {
+	c.m.Lock()
+	defer c.m.Unlock()
+	return StmtCacheStats{
+		Hits:   c.hits,
+		Misses: c.misses,
+		Len:    c.ll.Len(),
+	}
+}
+
+// evict removes the least recently used statements until the number of
+// statements is less than or equal to size.
+// It must be called with lock.
+func (c *stmtCache) evict(size int) {
+	for c.ll.Len() > size {
+		elem := c.ll.Back()
+		entry := c.ll.Remove(elem).(*stmtCacheEntry)
+		delete(c.entries, entry.query)
+		entry.evicted = true
+		if entry.refs == 0 {
+			entry.stmt.Close()
+		}
+	}
+}
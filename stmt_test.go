@@ -0,0 +1,251 @@
+package genmai
+
+import (
+	"context"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestDB_SetStmtCacheSize(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	var actual interface{} = db.StmtCacheStats()
+	var expect interface{} = StmtCacheStats{}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+	db.SetStmtCacheSize(2)
+	for i := 0; i < 3; i++ {
+		var results []testModel
+		if err := db.Select(&results, db.Where("id", "=", i)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{Hits: 2, Misses: 1, Len: 1}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+
+	// in transaction.
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	var results []testModel
+	if err := tx.Select(&results, tx.Where("id", "=", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{Hits: 3, Misses: 1, Len: 1}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+
+	// evict the least recently used.
+	for _, v := range []interface{}{
+		db.Where("name", "=", "test1"),
+		db.Where("addr", "=", "addr1"),
+	} {
+		var results []testModel
+		if err := db.Select(&results, v); err != nil {
+			t.Fatal(err)
+		}
+	}
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{Hits: 3, Misses: 3, Len: 2}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+	results = nil
+	if err := db.Select(&results, db.Where("id", "=", 1)); err != nil {
+		t.Fatal(err)
+	}
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{Hits: 3, Misses: 4, Len: 2}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+
+	db.SetStmtCacheSize(0)
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestDB_SetStmtCacheSize_inTx(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestDB_SetStmtCacheSize_inTx")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := testDB(filepath.Join(dir, "go_test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	db.SetStmtCacheSize(2)
+	selectInTx := func() {
+		if err := db.Transaction(func(tx *Tx) error {
+			var results []TestTable
+			return tx.Select(&results, tx.Where("name", "=", "test"))
+		}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	selectInTx()
+	actual := db.StmtCacheStats()
+	expect := StmtCacheStats{Misses: 1}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+	var results []TestTable
+	if err := db.Select(&results, db.Where("name", "=", "test")); err != nil {
+		t.Fatal(err)
+	}
+	selectInTx()
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{Hits: 1, Misses: 2, Len: 1}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestDB_SetStmtCacheSize_maxOpenConns(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	db.SetStmtCacheSize(2)
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	var results []testModel
+	if err := tx.SelectContext(ctx, &results, tx.Where("name", "=", "test1")); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	if actual, expect := len(results), 1; actual != expect {
+		t.Errorf(`Tx.Select(...) => %v rows; want %v`, actual, expect)
+	}
+}
+
+func Test_stmtCache_evictInUse(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	c := newStmtCache(1)
+	var entries []*stmtCacheEntry
+	for _, query := range []string{
+		`SELECT name FROM test_model WHERE id = 1`,
+		`SELECT addr FROM test_model WHERE id = 1`,
+	} {
+		st, err := db.db.Prepare(query)
+		if err != nil {
+			t.Fatal(err)
+		}
+		entries = append(entries, c.add(query, st))
+	}
+	entry := entries[0]
+	if err := c.release(entries[1]); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = entry.evicted
+	var expect interface{} = true
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`entry.evicted => %#v; want %#v`, actual, expect)
+	}
+	// evicted statement is still available until it is released.
+	var name string
+	if err := entry.stmt.QueryRow().Scan(&name); err != nil {
+		t.Fatal(err)
+	}
+	actual = name
+	expect = "test1"
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`name => %#v; want %#v`, actual, expect)
+	}
+	if err := c.release(entry); err != nil {
+		t.Fatal(err)
+	}
+	if err := entry.stmt.QueryRow().Scan(&name); err == nil {
+		t.Errorf(`QueryRow() of the released statement => nil; want error`)
+	}
+	c.purge()
+	actual = c.stats().Len
+	expect = 0
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`stats().Len => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestDB_SetPrepare(t *testing.T) {
+	db := newTestDB(t)
+	defer db.Close()
+	db.SetStmtCacheSize(10)
+	db.SetPrepare(false)
+	var results []testModel
+	if err := db.Select(&results, db.Where("id", "=", 1)); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Insert(&testModel{Name: "test10", Addr: "addr10"}); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = db.StmtCacheStats()
+	var expect interface{} = StmtCacheStats{}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+	actual = results
+	expect = []testModel{{1, "test1", "addr1"}}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.Select(&results) => %#v; want %#v`, actual, expect)
+	}
+
+	// override by WithPrepare.
+	results = nil
+	if err := db.SelectContext(WithPrepare(context.Background(), true), &results, db.Where("id", "=", 1)); err != nil {
+		t.Fatal(err)
+	}
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{Misses: 1, Len: 1}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+	db.SetPrepare(true)
+	results = nil
+	if err := db.SelectContext(WithPrepare(context.Background(), false), &results, db.Where("id", "=", 1)); err != nil {
+		t.Fatal(err)
+	}
+	actual = db.StmtCacheStats()
+	expect = StmtCacheStats{Misses: 1, Len: 1}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.StmtCacheStats() => %#v; want %#v`, actual, expect)
+	}
+}
@@ -0,0 +1,210 @@
+package genmai
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"sync"
+)
+
+// Tx represents a transaction that is started by DB.Begin.
+// Tx has the same query API as DB (Select, Insert, Update, Delete, Where and
+// so on), and all queries through it will be run in the transaction.
+// Each Tx is independent of other transactions, so a Tx must not be shared
+// by goroutines, but one DB can be used to start many transactions.
+//
+// Begin of Tx starts a nested transaction by using a savepoint.
+// Commit and Rollback of the nested transaction release and rollback to the
+// savepoint, and the outer transaction is still in progress.
+//
+// The settings of the connection pool can't be changed through Tx, so Close
+// of Tx returns an error, and the setters such as SetPrepare and
+// SetStmtCacheSize of Tx panic. Use them on the DB that started the
+// transaction.
+type Tx struct {
+	*txDB // bound to the transaction.
+
+	ctx       context.Context
+	savepoint string // name of the savepoint if it's a nested transaction.
+	mu        sync.Mutex
+	done      bool          // whether the transaction has been finished by finish.
+	finished  chan struct{} // closed when the transaction that is started by the SQL is finished.
+}
+
+// Commit commits the transaction.
+// If Commit or Rollback already called, Commit returns ErrTxDone.
+func (tx *Tx) Commit() error {
+	switch {
+	case tx.savepoint != "":
+		return tx.finish(tx.dialect.ReleaseSavePoint(tx.savepoint))
+	case tx.conn != nil:
+		return tx.finish("COMMIT")
+	}
+	if err := tx.tx.Commit(); err != sql.ErrTxDone {
+		return err
+	}
+	return ErrTxDone
+}
+
+// Rollback rollbacks the transaction.
+// If Commit or Rollback already called, Rollback returns ErrTxDone.
+func (tx *Tx) Rollback() error {
+	switch {
+	case tx.savepoint != "":
+		return tx.finish(tx.dialect.RollbackToSavePoint(tx.savepoint), tx.dialect.ReleaseSavePoint(tx.savepoint))
+	case tx.conn != nil:
+		return tx.finish("ROLLBACK")
+	}
+	if err := tx.tx.Rollback(); err != sql.ErrTxDone {
+		return err
+	}
+	return ErrTxDone
+}
+
+// finish finishes the nested transaction or the transaction that is started
+// by the SQL of Dialect.StartTransaction by the queries.
+// If the queries fail or ctx of the transaction is done, the transaction
+// that is started by the SQL will be discarded by discard.
+func (tx *Tx) finish(queries ...string) error {
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if tx.done {
+		return ErrTxDone
+	}
+	tx.done = true
+	if tx.savepoint != "" {
+		for _, query := range queries {
+			if _, err := tx.execDirect(tx.ctx, query); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+	defer close(tx.finished)
+	if err := tx.ctx.Err(); err != nil {
+		tx.discard()
+		return err
+	}
+	for _, query := range queries {
+		if _, err := tx.execDirect(tx.ctx, query); err != nil {
+			tx.discard()
+			return err
+		}
+	}
+	return tx.conn.Close()
+}
+
+// discard rollbacks the transaction that is started by the SQL of
+// Dialect.StartTransaction, and closes the connection without returning it
+// to the pool, because the transaction might be still open on it.
+func (tx *Tx) discard() {
+	tx.execDirect(context.Background(), "ROLLBACK")
+	tx.conn.Raw(func(driverConn interface{}) error {
+		return driver.ErrBadConn
+	})
+	tx.conn.Close()
+}
+
+// rollbackOnDone rollbacks the transaction that is started by the SQL of
+// Dialect.StartTransaction when ctx is done before Commit or Rollback is
+// called, like the transaction of the database/sql.
+func (tx *Tx) rollbackOnDone() {
+	select {
+	case <-tx.ctx.Done():
+		tx.finish("ROLLBACK")
+	case <-tx.finished:
+	}
+}
+
+// txDB is the DB that is embedded in Tx.
+// It is the unexported alias of DB to prevent the DB from being accessed as
+// the field of Tx, such as tx.DB.Close().
+type txDB = DB
+
+// Close returns an error because the database can't be closed in the
+// transaction. Use Commit or Rollback to finish the transaction.
+func (tx *Tx) Close() error {
+	return errTxPoolSetting("Close")
+}
+
+// SetPrepare panics. See Tx.
+func (tx *Tx) SetPrepare(prepare bool) {
+	panic(errTxPoolSetting("SetPrepare"))
+}
+
+// SetStmtCacheSize panics. See Tx.
+func (tx *Tx) SetStmtCacheSize(n int) {
+	panic(errTxPoolSetting("SetStmtCacheSize"))
+}
+
+// SetReturning panics. See Tx.
+func (tx *Tx) SetReturning(returning bool) {
+	panic(errTxPoolSetting("SetReturning"))
+}
+
+// SetTransactionRetry panics. See Tx.
+func (tx *Tx) SetTransactionRetry(n int) {
+	panic(errTxPoolSetting("SetTransactionRetry"))
+}
+
+// errTxPoolSetting returns the error of the method name that can't be
+// called on Tx.
+func errTxPoolSetting(name string) error {
+	return fmt.Errorf("%s: can't be called on the transaction. call it on the DB instead", name)
+}
+
+// Transaction starts a transaction and calls fn with it.
+// If fn returns nil, the transaction will be committed and Transaction
+// returns the error of the commit.
+// If fn returns an error, the transaction will be rolled back and
+// Transaction returns that error.
+// If fn panics, the transaction will be rolled back and Transaction panics
+// again with the same value.
+//
+// If the DB is already bound to a transaction, fn will be called in a nested
+// transaction that uses a savepoint. See BeginTx.
+//
+// If the number of retries is set by SetTransactionRetry, the whole
+// transaction will be retried when it fails with an error that
+// Dialect.IsRetryableError reports. So fn may be called more than once.
+// A nested transaction is never retried because a retry must start over the
+// outer transaction.
+func (db *DB) Transaction(fn func(tx *Tx) error) error {
+	return db.TransactionContext(context.Background(), nil, fn)
+}
+
+// TransactionContext is like Transaction, but with context and options.
+// ctx and opts are passed to BeginTx.
+func (db *DB) TransactionContext(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	for i := 0; ; i++ {
+		err := db.transaction(ctx, opts, fn)
+		if err == nil || db.inTx() || i >= db.txRetries || !db.dialect.IsRetryableError(err) || ctx.Err() != nil {
+			return err
+		}
+	}
+}
+
+// SetTransactionRetry sets the maximum number of retries of Transaction.
+// By default, Transaction never retries.
+func (db *DB) SetTransactionRetry(n int) {
+	db.txRetries = n
+}
+
+func (db *DB) transaction(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	defer func() {
+		if e := recover(); e != nil {
+			tx.Rollback()
+			panic(e)
+		}
+	}()
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
@@ -0,0 +1,520 @@
+package genmai
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+)
+
+func TestTx_Commit(t *testing.T) {
+	func() {
+		db, err := testDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		actual := tx.Commit()
+		expect := ErrTxDone
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Tx.Commit() => %#v; want %#v`, actual, expect)
+		}
+	}()
+
+	func() {
+		db, err := testDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		actual := tx.Commit()
+		expect := ErrTxDone
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Tx.Commit() => %#v; want %#v`, actual, expect)
+		}
+	}()
+}
+
+func TestTx_Rollback(t *testing.T) {
+	func() {
+		db, err := testDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		actual := tx.Rollback()
+		expect := ErrTxDone
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Tx.Rollback() => %#v; want %#v`, actual, expect)
+		}
+	}()
+
+	func() {
+		db, err := testDB()
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer db.Close()
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatal(err)
+		}
+		actual := tx.Rollback()
+		expect := ErrTxDone
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Tx.Rollback() => %#v; want %#v`, actual, expect)
+		}
+	}()
+}
+
+func TestTx_independent(t *testing.T) {
+	dir, err := ioutil.TempDir("", "TestTx_independent")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := testDB(filepath.Join(dir, "go_test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	tx1, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx1.Insert(&TestTable{Name: "tx1"}); err != nil {
+		t.Fatal(err)
+	}
+	var results []TestTable
+	if err := db.Select(&results); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = len(results)
+	var expect interface{} = 0
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`len(results) => %#v; want %#v`, actual, expect)
+	}
+	if err := tx1.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	tx2, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx2.Insert(&TestTable{Name: "tx2"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := tx2.Commit(); err != nil {
+		t.Fatal(err)
+	}
+	results = nil
+	if err := db.Select(&results, db.Where("name", "=", "tx2")); err != nil {
+		t.Fatal(err)
+	}
+	actual = len(results)
+	expect = 1
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`len(results) => %#v; want %#v`, actual, expect)
+	}
+	results = nil
+	if err := db.Select(&results, db.Where("name", "=", "tx1")); err != nil {
+		t.Fatal(err)
+	}
+	actual = len(results)
+	expect = 0
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`len(results) => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestDB_Transaction(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	dir, err := ioutil.TempDir("", "TestDB_Transaction")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := testDB(filepath.Join(dir, "go_test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	count := func() int64 {
+		var n int64
+		if err := db.Select(&n, db.Count(), db.From(&TestTable{})); err != nil {
+			t.Fatal(err)
+		}
+		return n
+	}
+
+	// commit.
+	if err := db.Transaction(func(tx *Tx) error {
+		_, err := tx.Insert(&TestTable{Name: "commit"})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = count()
+	var expect interface{} = int64(1)
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`count => %#v; want %#v`, actual, expect)
+	}
+
+	// rollback by error.
+	errRollback := fmt.Errorf("rollback")
+	actual = db.Transaction(func(tx *Tx) error {
+		if _, err := tx.Insert(&TestTable{Name: "rollback"}); err != nil {
+			return err
+		}
+		return errRollback
+	})
+	expect = errRollback
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`DB.Transaction(fn) => %#v; want %#v`, actual, expect)
+	}
+	actual = count()
+	expect = int64(1)
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`count => %#v; want %#v`, actual, expect)
+	}
+
+	// rollback by panic.
+	func() {
+		defer func() {
+			actual := recover()
+			expect := "panic"
+			if !reflect.DeepEqual(actual, expect) {
+				t.Errorf(`recover() => %#v; want %#v`, actual, expect)
+			}
+		}()
+		db.Transaction(func(tx *Tx) error {
+			if _, err := tx.Insert(&TestTable{Name: "panic"}); err != nil {
+				return err
+			}
+			panic("panic")
+		})
+	}()
+	actual = count()
+	expect = int64(1)
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`count => %#v; want %#v`, actual, expect)
+	}
+}
+
+func TestDB_Transaction_retry(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, ok := db.dialect.(*SQLite3Dialect); !ok {
+		t.Skip("the retryable error is the error of SQLite3")
+	}
+	busy := &testDriverError{Code: 5}
+	notRetryable := fmt.Errorf("not retryable")
+	for _, v := range []struct {
+		retries     int
+		err         error
+		expectErr   error
+		expectCalls int
+	}{
+		{0, busy, busy, 1},
+		{2, busy, busy, 3},
+		{2, notRetryable, notRetryable, 1},
+		{2, nil, nil, 1},
+	} {
+		db.SetTransactionRetry(v.retries)
+		calls := 0
+		err := db.Transaction(func(tx *Tx) error {
+			calls++
+			return v.err
+		})
+		actual := []interface{}{err, calls}
+		expect := []interface{}{v.expectErr, v.expectCalls}
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`retries=%v: DB.Transaction(fn) => %#v, calls %#v; want %#v, calls %#v`, v.retries, err, calls, v.expectErr, v.expectCalls)
+		}
+	}
+}
+
+func TestTx_Begin_nested(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if _, err := tx.Insert(&TestTable{Name: "outer"}); err != nil {
+		t.Fatal(err)
+	}
+	nested, err := tx.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := nested.Insert(&TestTable{Name: "rollback"}); err != nil {
+		t.Fatal(err)
+	}
+	if err := nested.Rollback(); err != nil {
+		t.Fatal(err)
+	}
+	var actual interface{} = nested.Rollback()
+	var expect interface{} = ErrTxDone
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`Tx.Rollback() => %#v; want %#v`, actual, expect)
+	}
+	if err := tx.Transaction(func(tx *Tx) error {
+		_, err := tx.Insert(&TestTable{Name: "commit"})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var results []TestTable
+	if err := tx.Select(&results, tx.OrderBy("id", ASC)); err != nil {
+		t.Fatal(err)
+	}
+	names := make([]string, len(results))
+	for i, r := range results {
+		names[i] = r.Name
+	}
+	actual = names
+	expect = []string{"outer", "commit"}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`names => %#v; want %#v`, actual, expect)
+	}
+	if err := tx.Commit(); err != nil {
+		t.Fatal(err)
+	}
+}
+
+func TestDB_BeginTx_withOptions(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	dir, err := ioutil.TempDir("", "TestDB_BeginTx_withOptions")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := testDB(filepath.Join(dir, "go_test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	for _, v := range []struct {
+		name   string
+		commit bool
+		expect int64
+	}{
+		{"rollback", false, 0},
+		{"commit", true, 1},
+	} {
+		tx, err := db.BeginTx(context.Background(), opts)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := tx.Insert(&TestTable{Name: v.name}); err != nil {
+			t.Fatal(err)
+		}
+		nested, err := tx.Begin()
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err := nested.Insert(&TestTable{Name: "nested"}); err != nil {
+			t.Fatal(err)
+		}
+		if err := nested.Rollback(); err != nil {
+			t.Fatal(err)
+		}
+		if v.commit {
+			err = tx.Commit()
+		} else {
+			err = tx.Rollback()
+		}
+		if err != nil {
+			t.Fatal(err)
+		}
+		var actual interface{} = tx.Commit()
+		var expect interface{} = ErrTxDone
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`Tx.Commit() => %#v; want %#v`, actual, expect)
+		}
+		var n int64
+		if err := db.Select(&n, db.Count(), db.From(&TestTable{}), db.Where("name", "=", v.name)); err != nil {
+			t.Fatal(err)
+		}
+		actual = n
+		expect = v.expect
+		if !reflect.DeepEqual(actual, expect) {
+			t.Errorf(`%s: count => %#v; want %#v`, v.name, actual, expect)
+		}
+	}
+}
+
+func TestTx_poolSettings(t *testing.T) {
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	tx, err := db.Begin()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer tx.Rollback()
+	if err := tx.Close(); err == nil {
+		t.Errorf(`Tx.Close() => nil; want error`)
+	}
+	for name, fn := range map[string]func(){
+		"SetPrepare":          func() { tx.SetPrepare(false) },
+		"SetStmtCacheSize":    func() { tx.SetStmtCacheSize(0) },
+		"SetReturning":        func() { tx.SetReturning(true) },
+		"SetTransactionRetry": func() { tx.SetTransactionRetry(1) },
+	} {
+		func() {
+			defer func() {
+				if err := recover(); err == nil {
+					t.Errorf(`Tx.%s(...) => no panic; want panic`, name)
+				}
+			}()
+			fn()
+		}()
+	}
+	if _, err := tx.execDirect(context.Background(), "SELECT 1"); err != nil {
+		t.Fatalf("query after Tx.Close() => %v; want nil", err)
+	}
+}
+
+func TestDB_BeginTx_cancel(t *testing.T) {
+	type TestTable struct {
+		Id   int64 `db:"pk"`
+		Name string
+	}
+	dir, err := ioutil.TempDir("", "TestDB_BeginTx_cancel")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+	db, err := testDB(filepath.Join(dir, "go_test.db"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	db.DB().SetMaxOpenConns(1)
+	for _, query := range []string{
+		`DROP TABLE IF EXISTS test_table`,
+		createTableString("test_table", "name text"),
+	} {
+		if _, err := db.db.Exec(query); err != nil {
+			t.Fatal(fmt.Errorf("%v: %s", err, query))
+		}
+	}
+	opts := &sql.TxOptions{Isolation: sql.LevelSerializable}
+	ctx, cancel := context.WithCancel(context.Background())
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tx.Insert(&TestTable{Name: "cancel"}); err != nil {
+		t.Fatal(err)
+	}
+	cancel()
+	if err := tx.Commit(); err == nil {
+		t.Errorf(`Tx.Commit() after cancel => nil; want error`)
+	}
+
+	// the connection must not be returned to the pool with the open transaction.
+	if err := db.TransactionContext(context.Background(), opts, func(tx *Tx) error {
+		_, err := tx.Insert(&TestTable{Name: "commit"})
+		return err
+	}); err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	var results []TestTable
+	if err := db.Select(&results); err != nil {
+		t.Fatal(err)
+	}
+	for _, r := range results {
+		names = append(names, r.Name)
+	}
+	if actual, expect := names, []string{"commit"}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf(`names => %#v; want %#v`, actual, expect)
+	}
+}
@@ -0,0 +1,209 @@
+package genmai
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// UpsertOptions represents the options of Upsert.
+type UpsertOptions struct {
+	// Conflict is the column names of the conflict target.
+	// The columns must be specified "pk" or "unique" struct tag.
+	// If it is empty, the columns of the primary key will be used, or the
+	// unique column if the primary key is auto-incrementable. It must be
+	// specified if the primary key is auto-incrementable and there are
+	// multiple unique columns.
+	// MySQL ignores it, because ON DUPLICATE KEY UPDATE is applied to the
+	// conflict of any unique index.
+	Conflict []string
+
+	// Update is the column names to update on conflict.
+	// If it is nil, all columns excluding the conflict target will be updated.
+	// If there are no columns to update, Upsert returns an error unless
+	// DoNothing is true.
+	Update []string
+
+	// If DoNothing is true, the conflicted rows will be left as is.
+	DoNothing bool
+}
+
+// Upsert inserts one or more records to the database table, or updates the
+// existing records on conflict.
+// The obj must be pointer to struct or slice of struct as well as Insert.
+// opts can be nil.
+// As well as Insert, the values are split into several statements so that
+// the number of parameters doesn't exceed Dialect.MaxParameters.
+// If the primary key is auto-incrementable and isn't the conflict target,
+// the IDs of the inserted or conflicted rows will be selected by the conflict
+// target and set to the primary key of objs.
+// Upsert returns the number of rows affected. Note that it depends on the
+// database how to count the updated rows.
+//
+//     db.Upsert(&user, &genmai.UpsertOptions{Conflict: []string{"email"}})
+func (db *DB) Upsert(obj interface{}, opts *UpsertOptions) (affected int64, err error) {
+	return db.UpsertContext(context.Background(), obj, opts)
+}
+
+// UpsertContext is like Upsert, but with context.
+func (db *DB) UpsertContext(ctx context.Context, obj interface{}, opts *UpsertOptions) (affected int64, err error) {
+	objs, m, err := db.tableObjs("Upsert", obj)
+	if err != nil {
+		return -1, err
+	}
+	if len(objs) < 1 {
+		return 0, nil
+	}
+	if opts == nil {
+		opts = &UpsertOptions{}
+	}
+	conflict, err := db.conflictColumns(m, opts.Conflict)
+	if err != nil {
+		return -1, err
+	}
+	var update []string
+	if !opts.DoNothing {
+		if update = opts.Update; update == nil {
+			update = db.upsertUpdateColumns(m, conflict)
+		} else if _, err := db.fieldsByColumns("Upsert", m, update); err != nil {
+			return -1, err
+		}
+		if len(update) < 1 {
+			return -1, fmt.Errorf("Upsert: no columns to update on conflict: DoNothing must be specified to leave the conflicted rows as is")
+		}
+	}
+	for _, obj := range objs {
+		if hook, ok := obj.(BeforeInserter); ok {
+			if err := hook.BeforeInsert(); err != nil {
+				return -1, err
+			}
+		}
+	}
+	affected, err = db.inChunks(ctx, m, objs, func(db *DB, objs []interface{}) (int64, error) {
+		return db.upsert(ctx, m, objs, conflict, update)
+	})
+	if err != nil {
+		return affected, err
+	}
+	for _, obj := range objs {
+		if hook, ok := obj.(AfterInserter); ok {
+			if err := hook.AfterInsert(); err != nil {
+				return affected, err
+			}
+		}
+	}
+	return affected, nil
+}
+
+// upsert upserts objs by a statement, and sets the auto-incremented IDs to
+// objs if the primary key isn't the conflict target.
+func (db *DB) upsert(ctx context.Context, m *model, objs []interface{}, conflict, update []string) (affected int64, err error) {
+	query, args := db.insertQuery(m, objs)
+	query = fmt.Sprintf("%s %s", query, db.dialect.OnConflict(conflict, update))
+	result, err := db.exec(ctx, query, args...)
+	if err != nil {
+		return -1, err
+	}
+	affected, _ = result.RowsAffected()
+	pk := m.autoIncrementPK()
+	if pk == nil {
+		return affected, nil
+	}
+	keys := make([]*modelField, len(conflict))
+	for i, column := range conflict {
+		if keys[i] = m.fieldByColumn(column); keys[i] == pk {
+			return affected, nil
+		}
+	}
+	return affected, db.setIDsByKeys(ctx, m, pk, keys, objs)
+}
+
+// setIDsByKeys selects the auto-incremented IDs of the rows that have the
+// same values of the keys as objs, and sets them to the primary key of objs.
+// The keys must identify a row.
+func (db *DB) setIDsByKeys(ctx context.Context, m *model, pk *modelField, keys []*modelField, objs []interface{}) error {
+	columns := []string{db.dialect.Quote(pk.column)}
+	for _, f := range keys {
+		columns = append(columns, db.dialect.Quote(f.column))
+	}
+	where, args := db.keyPredicate(0, keys, objs)
+	query := fmt.Sprintf("SELECT %s FROM %s WHERE %s", strings.Join(columns, ", "), db.dialect.Quote(m.tableName), where)
+	rows, done, err := db.query(ctx, query, args...)
+	if err != nil {
+		return err
+	}
+	defer done()
+	defer rows.Close()
+	ids := make(map[string]int64, len(objs))
+	var id int64
+	row := reflect.New(m.typ).Elem()
+	dest := []interface{}{&id}
+	for _, f := range keys {
+		dest = append(dest, row.FieldByIndex(f.index).Addr().Interface())
+	}
+	for rows.Next() {
+		if err := rows.Scan(dest...); err != nil {
+			return err
+		}
+		ids[fieldsKey(keys, row)] = id
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+	for _, obj := range objs {
+		if id, ok := ids[fieldsKey(keys, reflect.Indirect(reflect.ValueOf(obj)))]; ok {
+			setID(obj, pk, id)
+		}
+	}
+	return nil
+}
+
+// conflictColumns returns the column names of the conflict target.
+// If columns is empty, it returns the default conflict target.
+func (db *DB) conflictColumns(m *model, columns []string) ([]string, error) {
+	if len(columns) > 0 {
+		for _, column := range columns {
+			f := m.fieldByColumn(column)
+			if f == nil || !(f.pk || f.unique) {
+				return nil, fmt.Errorf(`Upsert: column "%s" of conflict target must be specified "pk" or "unique" tag`, column)
+			}
+		}
+		return columns, nil
+	}
+	if m.autoIncrementPK() == nil {
+		for _, f := range m.pks {
+			columns = append(columns, f.column)
+		}
+	} else {
+		for _, f := range m.fields {
+			if f.unique {
+				columns = append(columns, f.column)
+			}
+		}
+		// each unique column has its own index, so they can't be the
+		// conflict target together.
+		if len(columns) > 1 {
+			return nil, fmt.Errorf(`Upsert: conflict target is ambiguous: Conflict of UpsertOptions must be specified for the multiple "unique" columns %q`, columns)
+		}
+	}
+	if len(columns) < 1 {
+		return nil, fmt.Errorf(`Upsert: conflict target isn't found: "pk" or "unique" struct tag must be specified`)
+	}
+	return columns, nil
+}
+
+// upsertUpdateColumns returns the column names to update on conflict.
+// i.e. columns of the inserted values excluding the conflict target.
+func (db *DB) upsertUpdateColumns(m *model, conflict []string) (columns []string) {
+	excludes := make(map[string]bool, len(conflict))
+	for _, column := range conflict {
+		excludes[column] = true
+	}
+	for _, f := range m.valueFields {
+		if !excludes[f.column] {
+			columns = append(columns, f.column)
+		}
+	}
+	return columns
+}
@@ -0,0 +1,178 @@
+package genmai
+
+import (
+	"bytes"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestDB_Upsert(t *testing.T) {
+	type TestTable struct {
+		Id    int64  `db:"pk"`
+		Email string `db:"unique"`
+		Name  string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS test_table`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTable(&TestTable{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Insert(&TestTable{Email: "alice@example.com", Name: "alice"}); err != nil {
+		t.Fatal(err)
+	}
+
+	// single.
+	if _, err := db.Upsert(&TestTable{Email: "alice@example.com", Name: "updated"}, nil); err != nil {
+		t.Fatal(err)
+	}
+	// bulk.
+	objs := []TestTable{
+		{Email: "alice@example.com", Name: "bulk"},
+		{Email: "bob@example.com", Name: "bob"},
+	}
+	if _, err := db.Upsert(objs, &UpsertOptions{Conflict: []string{"email"}}); err != nil {
+		t.Fatal(err)
+	}
+	ids := func() map[string]int64 {
+		var rows []TestTable
+		if err := db.Select(&rows); err != nil {
+			t.Fatal(err)
+		}
+		ids := map[string]int64{}
+		for _, row := range rows {
+			ids[row.Email] = row.Id
+		}
+		return ids
+	}()
+	for _, obj := range objs {
+		if actual, expect := obj.Id, ids[obj.Email]; actual != expect {
+			t.Errorf("DB.Upsert(...); %s: Id => %v; want %v", obj.Email, actual, expect)
+		}
+	}
+	// do nothing.
+	objs = []TestTable{
+		{Email: "bob@example.com", Name: "ignored"},
+		{Email: "carol@example.com", Name: "carol"},
+	}
+	if _, err := db.Upsert(objs, &UpsertOptions{DoNothing: true}); err != nil {
+		t.Fatal(err)
+	}
+	var actual []TestTable
+	if err := db.Select(&actual, []string{"email", "name"}, db.OrderBy("email", ASC)); err != nil {
+		t.Fatal(err)
+	}
+	expect := []TestTable{
+		{Email: "alice@example.com", Name: "bulk"},
+		{Email: "bob@example.com", Name: "bob"},
+		{Email: "carol@example.com", Name: "carol"},
+	}
+	if !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(...) => %#v; want %#v", actual, expect)
+	}
+
+	for _, opts := range []*UpsertOptions{
+		{Conflict: []string{"name"}},
+		{Update: []string{"unknown"}},
+		{Update: []string{}},
+	} {
+		if _, err := db.Upsert(&TestTable{Email: "alice@example.com"}, opts); err == nil {
+			t.Errorf("DB.Upsert(obj, %#v) => _, nil; want error", opts)
+		}
+	}
+}
+
+func TestDB_Upsert_multipleUnique(t *testing.T) {
+	type TestTable struct {
+		Id    int64  `db:"pk"`
+		Email string `db:"unique"`
+		Name  string `db:"unique"`
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS test_table`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTable(&TestTable{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Upsert(&TestTable{Email: "alice@example.com", Name: "alice"}, nil); err == nil {
+		t.Errorf("DB.Upsert(obj, nil) => _, nil; want error")
+	}
+	for _, name := range []string{"alice", "updated"} {
+		obj := &TestTable{Email: "alice@example.com", Name: name}
+		if _, err := db.Upsert(obj, &UpsertOptions{Conflict: []string{"email"}}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	var actual []TestTable
+	if err := db.Select(&actual); err != nil {
+		t.Fatal(err)
+	}
+	if expect := []TestTable{{Id: 1, Email: "alice@example.com", Name: "updated"}}; !reflect.DeepEqual(actual, expect) {
+		t.Errorf("DB.Select(...) => %#v; want %#v", actual, expect)
+	}
+}
+
+func TestDB_Upsert_chunk(t *testing.T) {
+	type TestTable struct {
+		Id    int64  `db:"pk"`
+		Email string `db:"unique"`
+		Name  string
+	}
+	db, err := testDB()
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer db.Close()
+	if _, ok := db.dialect.(*SQLite3Dialect); !ok {
+		t.Skip("testMaxParametersDialect is the dialect of SQLite3")
+	}
+	db.dialect = &testMaxParametersDialect{&SQLite3Dialect{}}
+	if _, err := db.db.Exec(`DROP TABLE IF EXISTS test_table`); err != nil {
+		t.Fatal(err)
+	}
+	if err := db.CreateTable(&TestTable{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := db.Insert(&TestTable{Email: "test3@example.com", Name: "test3"}); err != nil {
+		t.Fatal(err)
+	}
+	var buf bytes.Buffer
+	db.SetLogOutput(&buf)
+	db.SetLogFormat("{{.query}}")
+	objs := make([]TestTable, 5)
+	for i := range objs {
+		objs[i].Email = fmt.Sprintf("test%d@example.com", i+1)
+		objs[i].Name = "upsert"
+	}
+	if _, err := db.Upsert(objs, nil); err != nil {
+		t.Fatal(err)
+	}
+	// 2 rows (4 parameters) per statement.
+	if actual, expect := strings.Count(buf.String(), "INSERT INTO"), 3; actual != expect {
+		t.Errorf("number of INSERT => %v; want %v", actual, expect)
+	}
+	var actual []TestTable
+	if err := db.Select(&actual, db.OrderBy("email", ASC)); err != nil {
+		t.Fatal(err)
+	}
+	if len(actual) != len(objs) {
+		t.Fatalf("DB.Select(...) => %#v; want %v rows", actual, len(objs))
+	}
+	for i, obj := range objs {
+		if expect := actual[i]; !reflect.DeepEqual(obj, expect) {
+			t.Errorf("DB.Upsert(...); objs[%d] => %#v; want %#v", i, obj, expect)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package genmai
 
 import (
+	"database/sql"
 	"fmt"
 	"reflect"
 	"time"
@@ -9,6 +10,11 @@ import (
 
 var now = time.Now // for test.
 
+var (
+	timeType    = reflect.TypeOf(time.Time{})
+	scannerType = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+)
+
 // ToInterfaceSlice convert to []interface{} from []string.
 func ToInterfaceSlice(slice []string) []interface{} {
 	result := make([]interface{}, len(slice))
@@ -52,3 +58,27 @@ func flatten(args []interface{}) []interface{} {
 	}
 	return result
 }
+
+// driverErrorField returns the value of the named field of the error struct
+// that is returned from the database driver.
+// The value will be converted to int64, uint64 or string according to its kind.
+// It is to classify the errors without importing the driver packages.
+func driverErrorField(err error, name string) (interface{}, bool) {
+	if err == nil {
+		return nil, false
+	}
+	rv := reflect.Indirect(reflect.ValueOf(err))
+	if rv.Kind() != reflect.Struct {
+		return nil, false
+	}
+	field := rv.FieldByName(name)
+	switch field.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return field.Int(), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return field.Uint(), true
+	case reflect.String:
+		return field.String(), true
+	}
+	return nil, false
+}